@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/wacul/protter/prott"
+)
+
+func showAccount(ctx context.Context, client *prott.Client, output string) error {
+	account, err := client.Account(ctx)
+	if err != nil {
+		return err
+	}
+	if output == "json" {
+		return json.NewEncoder(os.Stdout).Encode(account)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "name:\t%s\n", account.Name)
+	fmt.Fprintf(w, "email:\t%s\n", account.Email)
+	fmt.Fprintf(w, "plan:\t%s\n", account.Plan)
+	fmt.Fprintf(w, "organization:\t%s\n", account.Organization)
+	fmt.Fprintf(w, "account id:\t%s\n", account.ID)
+	fmt.Fprintf(w, "rate limit:\t%s\n", account.RateLimit.Limit)
+	fmt.Fprintf(w, "rate limit remaining:\t%s\n", account.RateLimit.Remaining)
+	fmt.Fprintf(w, "rate limit reset:\t%s\n", account.RateLimit.Reset)
+	return w.Flush()
+}
@@ -0,0 +1,601 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wacul/protter/prott"
+)
+
+var scaleSuffixReg = regexp.MustCompile(`@(\d+(?:\.\d+)?)x$`)
+
+var scrollDirections = []string{"vertical", "horizontal", "both", "none"}
+
+var orientationLocks = []string{"portrait", "landscape", "none"}
+
+var statusBarStyles = []string{"light", "dark", "hidden"}
+
+// navBarConfig is an entry of --nav-bar-config-file.
+type navBarConfig struct {
+	Show  *bool  `json:"show"`
+	Title string `json:"title"`
+}
+
+// tabBarConfig is an entry of --tab-bar-config-file.
+type tabBarConfig struct {
+	Show  *bool    `json:"show"`
+	Items []string `json:"items"`
+}
+
+// tapHighlightConfig is an entry of --tap-highlight-map.
+type tapHighlightConfig struct {
+	Enabled *bool  `json:"enabled"`
+	Color   string `json:"color"`
+}
+
+var hexColorReg = regexp.MustCompile(`^#(?:[0-9A-Fa-f]{3}|[0-9A-Fa-f]{6}|[0-9A-Fa-f]{8})$`)
+
+func validateHexColor(color string) error {
+	if !hexColorReg.MatchString(color) {
+		return fmt.Errorf("invalid color %q: must be #rgb, #rrggbb or #rrggbbaa", color)
+	}
+	return nil
+}
+
+var keyboardTypes = []string{"default", "numeric", "email", "url"}
+
+// keyboardConfig is the content of <screen>.keyboard.json.
+type keyboardConfig struct {
+	Show bool   `json:"show"`
+	Type string `json:"type"`
+}
+
+// pullToRefreshConfig is the content of <screen>.pull_to_refresh.json.
+type pullToRefreshConfig struct {
+	Enabled bool   `json:"enabled"`
+	Target  string `json:"target"` // a screen name
+}
+
+// navigationConfig is the content of <screen>.navigation.json.
+type navigationConfig struct {
+	BackGesture       bool   `json:"back_gesture_enabled"`
+	BackGestureTarget string `json:"back_gesture_target"` // a screen name
+}
+
+var hapticFeedbackTypes = []string{"none", "selection", "impact_light", "impact_medium", "impact_heavy", "notification"}
+
+// hapticsConfig is the content of <screen>.haptics.json.
+type hapticsConfig struct {
+	Type string `json:"type"`
+}
+
+// timingConfig is the content of <screen>.timing.json.
+type timingConfig struct {
+	IntervalMS *int `json:"interval_ms"`
+}
+
+var presentationStyles = []string{"fullscreen", "modal", "popover", "sheet"}
+
+// presentationConfig is the content of <screen>.presentation.json.
+type presentationConfig struct {
+	Style string `json:"style"`
+}
+
+// artboardOptions holds the metadata sent along with each exported artboard.
+type artboardOptions struct {
+	ArtboardID      bool
+	Manifest        string
+	SketchFile      string
+	sketchArtboards map[string]string // artboard name -> ID in SketchFile
+
+	Branch      string
+	Commit      string
+	SessionID   string
+	ArtifactURL string
+
+	RequiredFonts bool
+	ColorSwatch   bool
+	Spacing       bool
+	IconSet       bool
+	Shadow        bool
+	Motion        bool
+	Gestures      bool
+
+	BorderRadius optionalInt
+	SafeArea     safeArea
+	Orientation  string
+
+	OrientationLock    string
+	OrientationLockMap string
+
+	Scrollable         bool
+	ScrollDirection    string
+	ScrollDirectionMap string
+	Overflow           optionalBool
+	OverflowDirection  string
+
+	DevicePixelRatio float64
+	ScaleSuffixMap   string
+	scaleSuffixes    map[string]float64 // content of ScaleSuffixMap
+
+	PrototypeDevice    string
+	PrototypeDeviceMap string
+	StatusBarStyle     string
+	StatusBarStyleMap  string
+	StatusBarHeight    optionalInt
+	NavBar             optionalBool
+	NavBarConfig       string
+	TabBar             optionalBool
+	TabBarConfig       string
+	HomeIndicator      bool
+	HomeIndicatorMap   string
+	Keyboard           bool
+	PullToRefresh      bool
+	BackGesture        bool
+	HapticFeedback     bool
+	SlideShow          bool
+	SlideShowInterval  time.Duration
+	Presentation       bool
+	PinchZoom          optionalBool
+	TapHighlight       optionalBool
+	TapHighlightColor  string
+	TapHighlightMap    string
+
+	LoadingPlaceholder  string
+	LoadingPlaceholders bool
+
+	devicesMu sync.Mutex
+	devices   []Device // cache of getDeviceList
+}
+
+// fields builds the extra form fields of the screen upload for an artboard.
+func (o *artboardOptions) fields(ctx context.Context, client *prott.Client, project Project, screen, path string) (url.Values, error) {
+	fields := url.Values{}
+	if o.ArtboardID {
+		manifest := o.Manifest
+		if manifest == "" {
+			manifest = filepath.Join(filepath.Dir(path), "manifest.json")
+		}
+		var id string
+		if _, err := lookupScreenMap(manifest, filepath.Base(path), &id); err != nil {
+			return nil, err
+		}
+		if id != "" {
+			fields.Set("screen[sketch_artboard_id]", id)
+		}
+	}
+	if fields.Get("screen[sketch_artboard_id]") == "" && o.sketchArtboards != nil {
+		if id := o.sketchArtboardID(screen); id != "" {
+			fields.Set("screen[sketch_artboard_id]", id)
+		}
+	}
+	if o.Branch != "" {
+		fields.Set("screen[branch]", o.Branch)
+	}
+	if o.Commit != "" {
+		fields.Set("screen[git_commit]", o.Commit)
+	}
+	if o.SessionID != "" {
+		fields.Set("screen[session_id]", o.SessionID)
+	}
+	if o.ArtifactURL != "" {
+		fields.Set("session[artifact_url]", o.ArtifactURL)
+	}
+	if o.RequiredFonts {
+		fonts, err := readLines(sidecarPath(path, ".required_fonts.txt"))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fonts {
+			fields.Add("screen[required_fonts][]", f)
+		}
+	}
+	borderRadius := o.BorderRadius
+	if ok, err := lookupScreenMap(filepath.Join(filepath.Dir(path), "border_radius.json"), screen, &borderRadius.value); err != nil {
+		return nil, err
+	} else if ok {
+		borderRadius.set = true
+	}
+	if borderRadius.set {
+		fields.Set("screen[border_radius]", strconv.Itoa(borderRadius.value))
+	}
+	area := o.SafeArea
+	if ok, err := lookupScreenMap(filepath.Join(filepath.Dir(path), "safe_areas.json"), screen, &area); err != nil {
+		return nil, err
+	} else if ok {
+		area.set = true
+	}
+	if area.set {
+		fields.Set("screen[safe_area_top]", strconv.Itoa(area.Top))
+		fields.Set("screen[safe_area_bottom]", strconv.Itoa(area.Bottom))
+		fields.Set("screen[safe_area_left]", strconv.Itoa(area.Left))
+		fields.Set("screen[safe_area_right]", strconv.Itoa(area.Right))
+	}
+	hideHomeIndicator := !o.HomeIndicator
+	if o.HomeIndicatorMap != "" {
+		if _, err := lookupScreenMap(o.HomeIndicatorMap, screen, &hideHomeIndicator); err != nil {
+			return nil, err
+		}
+	}
+	fields.Set("screen[hide_home_indicator]", strconv.FormatBool(hideHomeIndicator))
+	if o.Keyboard {
+		var conf keyboardConfig
+		if ok, err := loadJSON(sidecarPath(path, ".keyboard.json"), &conf); err != nil {
+			return nil, err
+		} else if ok {
+			if conf.Type == "" {
+				conf.Type = "default"
+			}
+			if !contains(keyboardTypes, conf.Type) {
+				return nil, fmt.Errorf("invalid keyboard type of a screen %q: %q", screen, conf.Type)
+			}
+			fields.Set("screen[show_keyboard]", strconv.FormatBool(conf.Show))
+			fields.Set("screen[keyboard_type]", conf.Type)
+		}
+	}
+	if o.PullToRefresh {
+		var conf pullToRefreshConfig
+		if ok, err := loadJSON(sidecarPath(path, ".pull_to_refresh.json"), &conf); err != nil {
+			return nil, err
+		} else if ok {
+			fields.Set("screen[pull_to_refresh_enabled]", strconv.FormatBool(conf.Enabled))
+		}
+	}
+	if o.BackGesture {
+		var conf navigationConfig
+		if ok, err := loadJSON(sidecarPath(path, ".navigation.json"), &conf); err != nil {
+			return nil, err
+		} else if ok {
+			fields.Set("screen[back_gesture_enabled]", strconv.FormatBool(conf.BackGesture))
+		}
+	}
+	if o.HapticFeedback {
+		var conf hapticsConfig
+		if ok, err := loadJSON(sidecarPath(path, ".haptics.json"), &conf); err != nil {
+			return nil, err
+		} else if ok {
+			if !contains(hapticFeedbackTypes, conf.Type) {
+				return nil, fmt.Errorf("invalid haptic feedback type of a screen %q: %q", screen, conf.Type)
+			}
+			fields.Set("screen[haptic_feedback_type]", conf.Type)
+		}
+	}
+	interval := int(o.SlideShowInterval / time.Millisecond)
+	if o.SlideShow {
+		var conf timingConfig
+		if _, err := loadJSON(sidecarPath(path, ".timing.json"), &conf); err != nil {
+			return nil, err
+		}
+		if conf.IntervalMS != nil {
+			interval = *conf.IntervalMS
+		}
+	}
+	if interval < 0 {
+		return nil, fmt.Errorf("invalid auto-advance interval of a screen %q: %dms", screen, interval)
+	}
+	if interval > 0 {
+		fields.Set("screen[auto_advance_interval_ms]", strconv.Itoa(interval))
+	}
+	if o.Presentation {
+		var conf presentationConfig
+		if ok, err := loadJSON(sidecarPath(path, ".presentation.json"), &conf); err != nil {
+			return nil, err
+		} else if ok {
+			if !contains(presentationStyles, conf.Style) {
+				return nil, fmt.Errorf("invalid presentation style of a screen %q: %q", screen, conf.Style)
+			}
+			fields.Set("screen[presentation_style]", conf.Style)
+		}
+	}
+	if o.Gestures {
+		var g gestures
+		if _, err := loadJSON(sidecarPath(path, ".gestures.json"), &g); err != nil {
+			return nil, err
+		}
+		if g.ForceTouchStyle != "" {
+			if !contains(forceTouchStyles, g.ForceTouchStyle) {
+				return nil, fmt.Errorf("invalid force touch style of a screen %q: %q", screen, g.ForceTouchStyle)
+			}
+			fields.Set("screen[force_touch_style]", g.ForceTouchStyle)
+		}
+	}
+	pinchZoom := o.PinchZoom
+	if ok, err := lookupScreenMap(filepath.Join(filepath.Dir(path), "pinch_zoom.json"), screen, &pinchZoom.value); err != nil {
+		return nil, err
+	} else if ok {
+		pinchZoom.set = true
+	}
+	if pinchZoom.set {
+		fields.Set("screen[pinch_zoom_enabled]", strconv.FormatBool(pinchZoom.value))
+	}
+	tapHighlight := o.TapHighlight
+	tapHighlightColor := o.TapHighlightColor
+	if o.TapHighlightMap != "" {
+		var conf tapHighlightConfig
+		if _, err := lookupScreenMap(o.TapHighlightMap, screen, &conf); err != nil {
+			return nil, err
+		}
+		if conf.Enabled != nil {
+			tapHighlight = optionalBool{value: *conf.Enabled, set: true}
+		}
+		if conf.Color != "" {
+			if err := validateHexColor(conf.Color); err != nil {
+				return nil, fmt.Errorf("invalid tap highlight of a screen %q: %s", screen, err)
+			}
+			tapHighlightColor = conf.Color
+		}
+	}
+	if tapHighlightColor != "" && !tapHighlight.set {
+		// a color enables the highlight unless disabled explicitly
+		tapHighlight = optionalBool{value: true, set: true}
+	}
+	if tapHighlight.set {
+		fields.Set("screen[tap_highlight_enabled]", strconv.FormatBool(tapHighlight.value))
+	}
+	if tapHighlight.value && tapHighlightColor != "" {
+		fields.Set("screen[tap_highlight_color]", tapHighlightColor)
+	}
+	var width, height int
+	if o.Orientation == "" || o.Scrollable {
+		var err error
+		if width, height, err = imageSize(path); err != nil {
+			return nil, err
+		}
+	}
+	orientation := o.Orientation
+	if orientation == "" {
+		orientation = "portrait"
+		if width > height {
+			orientation = "landscape"
+		}
+	}
+	fields.Set("screen[orientation]", orientation)
+	orientationLock := o.OrientationLock
+	if o.OrientationLockMap != "" {
+		if _, err := lookupScreenMap(o.OrientationLockMap, screen, &orientationLock); err != nil {
+			return nil, err
+		}
+		if orientationLock != "" && !contains(orientationLocks, orientationLock) {
+			return nil, fmt.Errorf("invalid orientation lock of a screen %q: %q", screen, orientationLock)
+		}
+	}
+	if orientationLock != "" {
+		fields.Set("screen[orientation_lock]", orientationLock)
+	}
+	scrollDirection := o.ScrollDirection
+	if scrollDirection == "" && o.Scrollable {
+		// a scrollable artboard is exported longer than the viewport along its scroll axis
+		switch {
+		case height > width:
+			scrollDirection = "vertical"
+		case width > height:
+			scrollDirection = "horizontal"
+		default:
+			scrollDirection = "none"
+		}
+	}
+	if o.ScrollDirectionMap != "" {
+		if _, err := lookupScreenMap(o.ScrollDirectionMap, screen, &scrollDirection); err != nil {
+			return nil, err
+		}
+		if scrollDirection != "" && !contains(scrollDirections, scrollDirection) {
+			return nil, fmt.Errorf("invalid scroll direction of a screen %q: %q", screen, scrollDirection)
+		}
+	}
+	if scrollDirection != "" {
+		fields.Set("screen[scroll_direction]", scrollDirection)
+	}
+	if o.Overflow.set {
+		fields.Set("screen[overflow_scroll]", strconv.FormatBool(o.Overflow.value))
+		overflowDirection := o.OverflowDirection
+		if overflowDirection == "" && scrollDirection != "none" {
+			overflowDirection = scrollDirection
+		}
+		if o.Overflow.value && overflowDirection != "" {
+			fields.Set("screen[overflow_direction]", overflowDirection)
+		}
+	}
+	ratio := o.DevicePixelRatio
+	if ratio == 0 {
+		if _, ratio = o.detectScale(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))); ratio == 0 {
+			ratio = 1
+		}
+	}
+	fields.Set("screen[device_pixel_ratio]", strconv.FormatFloat(ratio, 'f', -1, 64))
+	device := o.PrototypeDevice
+	if o.PrototypeDeviceMap != "" {
+		if _, err := lookupScreenMap(o.PrototypeDeviceMap, screen, &device); err != nil {
+			return nil, err
+		}
+	}
+	if device != "" {
+		if err := o.validateDevice(ctx, client, device); err != nil {
+			return nil, err
+		}
+		fields.Set("screen[prototype_device_id]", device)
+	}
+	statusBarStyle := o.StatusBarStyle
+	if o.StatusBarStyleMap != "" {
+		if _, err := lookupScreenMap(o.StatusBarStyleMap, screen, &statusBarStyle); err != nil {
+			return nil, err
+		}
+		if !contains(statusBarStyles, statusBarStyle) {
+			return nil, fmt.Errorf("invalid status bar style of a screen %q: %q", screen, statusBarStyle)
+		}
+	}
+	fields.Set("screen[status_bar_style]", statusBarStyle)
+	if o.StatusBarHeight.set {
+		fields.Set("screen[status_bar_height]", strconv.Itoa(o.StatusBarHeight.value))
+	}
+	navBar := o.NavBar
+	var navBarTitle string
+	if o.NavBarConfig != "" {
+		var conf navBarConfig
+		if _, err := lookupScreenMap(o.NavBarConfig, screen, &conf); err != nil {
+			return nil, err
+		}
+		if conf.Show != nil {
+			navBar = optionalBool{value: *conf.Show, set: true}
+		}
+		navBarTitle = conf.Title
+	}
+	if navBar.set {
+		fields.Set("screen[show_nav_bar]", strconv.FormatBool(navBar.value))
+	}
+	if navBarTitle != "" {
+		fields.Set("screen[nav_bar_title]", navBarTitle)
+	}
+	tabBar := o.TabBar
+	var tabBarItems []string
+	if o.TabBarConfig != "" {
+		var conf tabBarConfig
+		if _, err := lookupScreenMap(o.TabBarConfig, screen, &conf); err != nil {
+			return nil, err
+		}
+		if conf.Show != nil {
+			tabBar = optionalBool{value: *conf.Show, set: true}
+		}
+		tabBarItems = conf.Items
+	}
+	if tabBar.set {
+		fields.Set("screen[show_tab_bar]", strconv.FormatBool(tabBar.value))
+	}
+	for _, item := range tabBarItems {
+		fields.Add("screen[tab_bar_items][]", item)
+	}
+	return fields, nil
+}
+
+// files lists the extra files of the screen upload for an artboard (field name -> file path).
+func (o *artboardOptions) files(path string) (map[string]string, error) {
+	files := map[string]string{}
+	placeholder := o.LoadingPlaceholder
+	if o.LoadingPlaceholders {
+		sibling := sidecarPath(path, ".loading.png")
+		if _, err := os.Stat(sibling); err == nil {
+			placeholder = sibling
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	if placeholder != "" {
+		files["screen[loading_placeholder]"] = placeholder
+	}
+	return files, nil
+}
+
+// isSidecar reports whether the image at path belongs to another artboard
+// instead of being an artboard itself.
+func (o *artboardOptions) isSidecar(path string) bool {
+	return o.LoadingPlaceholders && strings.HasSuffix(path, ".loading.png")
+}
+
+// loadScaleSuffixes reads the --scale-suffix-map.
+func (o *artboardOptions) loadScaleSuffixes() error {
+	if o.ScaleSuffixMap == "" {
+		return nil
+	}
+	_, err := loadJSON(o.ScaleSuffixMap, &o.scaleSuffixes)
+	return err
+}
+
+// detectScale finds the scale of an artboard from the suffix of its file name.
+// Suffixes from the --scale-suffix-map are stripped from the name; the default @Nx is kept.
+// The scale is 0 if the name has no suffix.
+func (o *artboardOptions) detectScale(name string) (string, float64) {
+	if o.scaleSuffixes != nil {
+		var longest string
+		for suffix := range o.scaleSuffixes {
+			if strings.HasSuffix(name, suffix) && len(suffix) > len(longest) {
+				longest = suffix
+			}
+		}
+		if longest == "" {
+			return name, 0
+		}
+		return strings.TrimSuffix(name, longest), o.scaleSuffixes[longest]
+	}
+	if mat := scaleSuffixReg.FindStringSubmatch(name); mat != nil {
+		ratio, _ := strconv.ParseFloat(mat[1], 64)
+		return name, ratio
+	}
+	return name, 0
+}
+
+// lookupScreenMap decodes the entry for the screen in a JSON file mapping screen
+// (or project) names to values, and reports whether the entry exists.
+func lookupScreenMap(path, screen string, v interface{}) (bool, error) {
+	var m map[string]json.RawMessage
+	if ok, err := loadJSON(path, &m); err != nil || !ok {
+		return false, err
+	}
+	raw, ok := m[screen]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return false, fmt.Errorf("invalid %s: %s: %s", path, screen, err)
+	}
+	return true, nil
+}
+
+// sidecarPath returns the path of a file placed beside the artboard image,
+// named after the screen with the suffix (e.g. "Home.png" -> "Home.colors.json").
+func sidecarPath(path, suffix string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + suffix
+}
+
+// loadJSON decodes a JSON file into v and reports whether the file exists.
+func loadJSON(path string, v interface{}) (bool, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("invalid %s: %s", path, err)
+	}
+	return true, nil
+}
+
+// readLines reads non-blank lines of a file. A missing file has no lines.
+func readLines(path string) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, l := range strings.Split(string(data), "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines, nil
+}
+
+// newUUID generates a random (version 4) UUID.
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
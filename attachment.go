@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+
+	"github.com/wacul/protter/prott"
+)
+
+// colorSwatch is a color used in a screen.
+type colorSwatch struct {
+	Name  string `json:"name"`
+	Hex   string `json:"hex"`
+	Usage string `json:"usage"`
+}
+
+// shadow is a shadow (elevation) used in a screen.
+type shadow struct {
+	Name    string  `json:"name"`
+	XOffset float64 `json:"x_offset"`
+	YOffset float64 `json:"y_offset"`
+	Blur    float64 `json:"blur"`
+	Spread  float64 `json:"spread"`
+	Color   string  `json:"color"`
+	Opacity float64 `json:"opacity"`
+}
+
+// motion is the entry/exit animation of a screen.
+type motion struct {
+	Type       string `json:"type"`
+	Direction  string `json:"direction"`
+	DurationMS int    `json:"duration_ms"`
+	Easing     string `json:"easing"`
+}
+
+// attach posts the design tokens found beside the artboard image to the uploaded screen.
+func (o *artboardOptions) attach(ctx context.Context, client *prott.Client, screen Screen, path string) error {
+	if o.ColorSwatch {
+		if err := attachSidecar(ctx, client, screen, sidecarPath(path, ".colors.json"), "color_swatches", &[]colorSwatch{}); err != nil {
+			return err
+		}
+	}
+	if o.Spacing {
+		// the spacing API is not available on every Prott plan
+		err := attachSidecar(ctx, client, screen, sidecarPath(path, ".spacing.json"), "spacings", &map[string]int{})
+		if err != nil && !prott.IsNotFound(err) {
+			return err
+		}
+	}
+	if o.IconSet {
+		if err := attachSidecar(ctx, client, screen, sidecarPath(path, ".icons.json"), "icon_references", &[]string{}); err != nil {
+			return err
+		}
+	}
+	if o.Shadow {
+		if err := attachSidecar(ctx, client, screen, sidecarPath(path, ".shadows.json"), "shadows", &[]shadow{}); err != nil {
+			return err
+		}
+	}
+	if o.Motion {
+		if err := attachSidecar(ctx, client, screen, sidecarPath(path, ".motion.json"), "motion", &motion{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachSidecar decodes the sidecar file into v and posts it to a sub resource of the screen.
+// A missing sidecar file is skipped.
+func attachSidecar(ctx context.Context, client *prott.Client, screen Screen, sidecar, resource string, v interface{}) error {
+	ok, err := loadJSON(sidecar, v)
+	if err != nil || !ok {
+		return err
+	}
+	return client.PostScreenResource(ctx, screen, resource, v)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// credentialService is the service the credentials of protter are saved as in the OS credential store.
+const credentialService = "protter"
+
+// apiKeyAccount is the account the API key is saved as, beside the passwords saved by their emails.
+const apiKeyAccount = "api-key"
+
+var errNotTerminal = errors.New("not a terminal")
+
+// credentialStore is the OS credential store: the Keychain by security(1) on macOS,
+// and the Secret Service (e.g. GNOME Keyring) by secret-tool(1) elsewhere.
+type credentialStore struct{}
+
+// get returns the secret saved for the account, and reports whether there is one.
+func (credentialStore) get(account string) (string, bool, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("security", "find-generic-password", "-s", credentialService, "-a", account, "-w")
+	} else {
+		cmd = exec.Command("secret-tool", "lookup", "service", credentialService, "account", account)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if _, ok := err.(*exec.ExitError); ok && len(out) == 0 {
+		// both exit with an error status for a missing item
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read the credential store: %s", commandError(err, stderr.Bytes()))
+	}
+	return strings.TrimRight(string(out), "\n"), true, nil
+}
+
+func (credentialStore) set(account, secret string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		// security takes the secret only as an argument, which other users can see by ps(1):
+		// give the command to its interactive mode on the standard input instead, with the secret in hex not to quote it
+		cmd = exec.Command("security", "-i")
+		cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -l %s -X %s\n",
+			securityQuote(credentialService), securityQuote(account), securityQuote("protter "+account), hex.EncodeToString([]byte(secret))))
+	} else {
+		cmd = exec.Command("secret-tool", "store", "--label=protter "+account, "service", credentialService, "account", account)
+		cmd.Stdin = strings.NewReader(secret)
+	}
+	out, err := cmd.CombinedOutput()
+	if err == nil && runtime.GOOS == "darwin" && len(bytes.TrimSpace(out)) > 0 {
+		// security -i exits with 0 even if the command fails, which prints an error
+		err = errors.New("security failed")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save to the credential store: %s", commandError(err, out))
+	}
+	return nil
+}
+
+// securityQuote quotes an argument of a command of security -i.
+func securityQuote(arg string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+// commandError describes the error of a command with its output.
+func commandError(err error, out []byte) string {
+	if msg := strings.TrimSpace(string(out)); msg != "" {
+		return err.Error() + ": " + msg
+	}
+	return err.Error()
+}
+
+// prompt asks the question on the terminal, and returns the answer.
+// With secret, the answer is not echoed.
+func prompt(question string, secret bool) (string, error) {
+	if !isTerminal() {
+		return "", errNotTerminal
+	}
+	fmt.Fprint(os.Stderr, question)
+	if secret {
+		if err := stty("-echo"); err != nil {
+			return "", err
+		}
+		defer func() {
+			stty("echo")
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(answer, "\r\n"), nil
+}
+
+// isTerminal reports whether the standard input is a terminal.
+// A character device like /dev/null is not, which stty tells.
+func isTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	cmd := exec.Command("stty", "-g")
+	cmd.Stdin = os.Stdin
+	err = cmd.Run()
+	if errors.Is(err, exec.ErrNotFound) {
+		return true // no stty to tell
+	}
+	return err == nil
+}
+
+func stty(arg string) error {
+	cmd := exec.Command("stty", arg)
+	cmd.Stdin = os.Stdin
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to hide the input: %s", err)
+	}
+	return nil
+}
+
+// credentials returns the email and the password to sign in with.
+// A missing email is asked on the terminal, and a missing password is read from the credential store with keychain,
+// or asked on the terminal.
+func credentials(email, password string, keychain bool) (string, string, error) {
+	var err error
+	if email == "" {
+		if email, err = prompt("Email: ", false); err == errNotTerminal {
+			return "", "", usageError{errors.New("--prott-email is required to sign in (or use --project-api-key)")}
+		} else if err != nil {
+			return "", "", err
+		}
+	}
+	if password == "" && keychain {
+		if password, _, err = (credentialStore{}).get(email); err != nil {
+			return "", "", err
+		}
+	}
+	if password == "" {
+		if password, err = prompt("Password for "+email+": ", true); err == errNotTerminal {
+			return "", "", usageError{errors.New("--prott-password is required to sign in on no terminal (or use --keychain or --project-api-key)")}
+		} else if err != nil {
+			return "", "", err
+		}
+	}
+	return email, password, nil
+}
@@ -0,0 +1,15 @@
+package main
+
+import "testing"
+
+func TestSecurityQuote(t *testing.T) {
+	for _, tt := range []struct{ arg, want string }{
+		{"protter", `"protter"`},
+		{"protter a@example.com", `"protter a@example.com"`},
+		{`a"b\c`, `"a\"b\\c"`},
+	} {
+		if got := securityQuote(tt.arg); got != tt.want {
+			t.Errorf("securityQuote(%q) = %s, want %s", tt.arg, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/wacul/protter/prott"
+)
+
+const minRetryDelay = time.Second
+
+// retryPolicy is how the requests to Prott failed with a transient error are retried.
+type retryPolicy struct {
+	Count    int           // retries after the first attempt
+	MaxDelay time.Duration // between the attempts, except what Retry-After asks for
+	Timeout  time.Duration // of each attempt, or 0 for none
+}
+
+// retryDelay returns how long to wait before retrying after the response or err,
+// or false if they are not transient (only network errors and HTTP 429 or 5xx are).
+// The delay doubles from 1s with every attempt (up to MaxDelay), and is jittered
+// not to retry the parallel uploads all at once. A Retry-After of 429 is honored as it is.
+func (p retryPolicy) retryDelay(res *http.Response, err error, attempt int) (time.Duration, bool) {
+	switch {
+	case err != nil:
+		var ne net.Error
+		if !errors.As(err, &ne) {
+			return 0, false
+		}
+	case res.StatusCode == http.StatusTooManyRequests:
+		if d := prott.ParseRetryAfter(res.Header.Get("Retry-After")); d > 0 {
+			return d, true
+		}
+	case res.StatusCode/100 == 5:
+		// retry
+	default:
+		return 0, false
+	}
+	delay := minRetryDelay << uint(attempt-1)
+	if delay > p.MaxDelay || delay <= 0 {
+		delay = p.MaxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1)), true
+}
+
+// retryTransport retries the requests failed with a transient error by the retryPolicy.
+// The bodies of the requests are rebuilt with GetBody, which http.NewRequest sets for in-memory bodies
+// and the prott package for the streamed multipart bodies.
+type retryTransport struct {
+	policy retryPolicy
+	log    *logger
+	base   http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	attempts := t.policy.Count + 1
+	for attempt := 1; ; attempt++ {
+		res, err := t.roundTrip(req)
+		if attempt >= attempts || req.Context().Err() != nil {
+			return res, err
+		}
+		delay, ok := t.policy.retryDelay(res, err, attempt)
+		if !ok || (req.Body != nil && req.GetBody == nil) {
+			return res, err
+		}
+		reason := errorField(err)
+		if err == nil {
+			reason = res.Status
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+		t.log.warn("retry", logFields{"method": req.Method, "url": req.URL.String(), "attempt": attempt, "delay": delay.Seconds(), "error": reason},
+			"retrying %s %s in %s (attempt %d of %d): %s", req.Method, req.URL.Path, delay.Round(time.Millisecond), attempt+1, attempts, reason)
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		// a RoundTripper must not modify the given request
+		retry := req.Clone(req.Context())
+		if req.GetBody != nil {
+			if retry.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		req = retry
+	}
+}
+
+// roundTrip sends the request once, within the Timeout of the policy.
+func (t *retryTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	if t.policy.Timeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.policy.Timeout)
+	res, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// the body is read after RoundTrip returns
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+// cancelBody cancels the context of its request when closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/wacul/protter/prott"
+)
+
+// uploadScreenChunked uploads the artboard image in chunks of chunkSize bytes,
+// then creates (or updates) the screen from the uploaded image like uploadScreen.
+// It returns prott.ErrChunkedUploadUnsupported if Prott does not accept chunked uploads.
+func uploadScreenChunked(ctx context.Context, client *prott.Client, log *logger, project Project, screen, path, existingID string, fields url.Values, files map[string]string, chunkSize int64) (Screen, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Screen{}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return Screen{}, err
+	}
+
+	uploadID, err := client.InitiateUpload(ctx, filepath.Base(path), info.Size())
+	if err != nil {
+		return Screen{}, err
+	}
+	buf := make([]byte, chunkSize)
+	for start := int64(0); start < info.Size(); {
+		n, err := io.ReadFull(f, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return Screen{}, err
+		}
+		if err := client.UploadChunk(ctx, uploadID, buf[:n], start, info.Size()); err != nil {
+			return Screen{}, err
+		}
+		start += int64(n)
+	}
+
+	completed := url.Values{}
+	for name, values := range fields {
+		completed[name] = values
+	}
+	if existingID != "" {
+		completed.Set("screen[id]", existingID)
+	}
+	u := screenUpload(project, screen, path, completed, files)
+	return logUpload(log, "POST", project, screen, path, func() (Screen, *http.Response, error) {
+		return client.CompleteUpload(ctx, uploadID, u)
+	})
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+)
+
+// defaultConfigFile is read from the first --current-directory if --config is not given.
+const defaultConfigFile = ".protter.json"
+
+// projectMapping maps the directories under .exportedArtboards to a Prott project, e.g.
+// {"dir": "app-*", "project": "My App", "screen_prefix": "iOS / "}
+type projectMapping struct {
+	Dir          string `json:"dir"`           // a directory name or a glob pattern
+	Project      string `json:"project"`       // a name of the project
+	ProjectID    string `json:"project_id"`    // or its ID
+	ScreenPrefix string `json:"screen_prefix"` // prepended to the names of the screens
+}
+
+// config is the content of the --config file.
+type config struct {
+	Projects []projectMapping `json:"projects"` // the first matching mapping is used
+}
+
+// loadConfig reads the config file. A missing file is the empty config unless required.
+func loadConfig(file string, required bool) (config, error) {
+	var c config
+	ok, err := loadJSON(file, &c)
+	if err != nil {
+		return config{}, err
+	}
+	if !ok && required {
+		return config{}, fmt.Errorf("a config file %s is not exist", file)
+	}
+	for _, m := range c.Projects {
+		if m.Dir == "" || (m.Project == "" && m.ProjectID == "") {
+			return config{}, fmt.Errorf("invalid %s: a project mapping needs dir, and project or project_id", file)
+		}
+		if _, err := path.Match(m.Dir, ""); err != nil {
+			return config{}, fmt.Errorf("invalid %s: a dir %q: %s", file, m.Dir, err)
+		}
+	}
+	return c, nil
+}
+
+// resolveMappings names the projects mapped by their IDs after the projects on Prott.
+func resolveMappings(mappings []projectMapping, projectList []Project) ([]projectMapping, error) {
+	resolved := make([]projectMapping, len(mappings))
+	for i, m := range mappings {
+		if m.ProjectID != "" {
+			found := false
+			for _, p := range projectList {
+				if p.ID == m.ProjectID {
+					m.Project, found = p.Name, true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("a project of the ID %q mapped from %q is not exist", m.ProjectID, m.Dir)
+			}
+		}
+		resolved[i] = m
+	}
+	return resolved, nil
+}
+
+// mapProject returns the project the artboards in the directory are uploaded to,
+// and the prefix of their screen names. Directories without a mapping go to the project of the same name.
+func (s *syncer) mapProject(dir string) (string, string) {
+	for _, m := range s.mappings {
+		if ok, _ := path.Match(m.Dir, filepath.ToSlash(dir)); ok {
+			return m.Project, m.ScreenPrefix
+		}
+	}
+	return dir, ""
+}
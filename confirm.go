@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var errNotConfirmed = errors.New("the upload is not confirmed")
+
+// confirm asks the question on the terminal and fails unless it is answered yes.
+// Without a terminal (e.g. on CI) it fails unless yes is set.
+func confirm(question string, yes bool) error {
+	if yes {
+		return nil
+	}
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeCharDevice == 0 {
+		return errors.New("the upload is not confirmed: not a terminal (use --yes)")
+	}
+	fmt.Fprint(os.Stderr, question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return errNotConfirmed
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	default:
+		return errNotConfirmed
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wacul/protter/prott"
+)
+
+type Device = prott.Device
+
+// validateDevice checks that id is one of the prototype devices of Prott.
+// The device list is fetched on first use.
+func (o *artboardOptions) validateDevice(ctx context.Context, client *prott.Client, id string) error {
+	_, err := o.findDevice(ctx, client, id)
+	return err
+}
+
+func (o *artboardOptions) findDevice(ctx context.Context, client *prott.Client, id string) (Device, error) {
+	o.devicesMu.Lock()
+	defer o.devicesMu.Unlock()
+	if o.devices == nil {
+		devices, err := client.Devices(ctx)
+		if err != nil {
+			return Device{}, err
+		}
+		o.devices = devices
+	}
+	for _, d := range o.devices {
+		if d.ID == id {
+			return d, nil
+		}
+	}
+	return Device{}, fmt.Errorf("a prototype device %q is not exist", id)
+}
+
+// validateSplash checks that the splash screen image fits the device of the project
+// (--project-device-id, or --artboard-prototype-device-id without it)
+// at an integral scale (e.g. 750x1334 for a 375x667 device), in either orientation.
+func (o *artboardOptions) validateSplash(ctx context.Context, client *prott.Client, path, deviceID string) error {
+	if deviceID == "" {
+		return nil // no target device to compare with
+	}
+	device, err := o.findDevice(ctx, client, deviceID)
+	if err != nil {
+		return err
+	}
+	if device.Width == 0 || device.Height == 0 {
+		return nil
+	}
+	width, height, err := imageSize(path)
+	if err != nil {
+		return err
+	}
+	fits := func(w, h int) bool {
+		return w%device.Width == 0 && h%device.Height == 0 && w/device.Width == h/device.Height
+	}
+	if !fits(width, height) && !fits(height, width) {
+		return fmt.Errorf("a splash screen %s (%dx%d) does not fit the device %q (%dx%d)", path, width, height, device.ID, device.Width, device.Height)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wacul/protter/prott"
+)
+
+func TestValidateSplash(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id":"iphone8","name":"iPhone 8","width":375,"height":667}]`))
+	}))
+	defer srv.Close()
+	client := prott.NewClient(srv.Client())
+	client.BaseURL = srv.URL
+
+	for _, tt := range []struct {
+		width, height int
+		device        string
+		ok            bool
+	}{
+		{750, 1334, "iphone8", true},
+		{1334, 750, "iphone8", true},
+		{750, 1000, "iphone8", false},
+		{750, 1000, "", true}, // no device to compare with
+	} {
+		path := filepath.Join(t.TempDir(), "splash.png")
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := png.Encode(f, image.NewGray(image.Rect(0, 0, tt.width, tt.height))); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		var o artboardOptions
+		if err := o.validateSplash(context.Background(), client, path, tt.device); (err == nil) != tt.ok {
+			t.Errorf("%dx%d for %q: got %v, want ok=%v", tt.width, tt.height, tt.device, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/wacul/protter/prott"
+)
+
+// The exit codes of protter, to tell the kind of a failure in scripts and CI.
+const (
+	exitFailure        = 1 // any other error
+	exitUsage          = 2 // invalid flags or arguments
+	exitAuthFailure    = 3 // the login, the session or the API key is rejected
+	exitNetworkFailure = 4 // Prott cannot be reached
+	exitPartialFailure = 5 // some artboards failed to sync
+)
+
+// usageError is an error of the command line.
+type usageError struct {
+	error
+}
+
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if _, ok := err.(usageError); ok {
+		return exitUsage
+	}
+	if errs, ok := err.(uploadErrors); ok {
+		for _, err := range errs {
+			if _, ok := err.(missingProjectError); !ok {
+				return exitPartialFailure
+			}
+		}
+		return exitFailure // only the projects not exist are skipped
+	}
+	if errors.Is(err, prott.ErrInvalidLogin) || rejected(err) {
+		return exitAuthFailure
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return exitNetworkFailure
+	}
+	return exitFailure
+}
+
+// rejected reports whether Prott rejected the credentials (the session or the API key) of the request.
+func rejected(err error) bool {
+	if errors.Is(err, prott.ErrSessionRejected) {
+		return true
+	}
+	var se *prott.StatusError
+	return errors.As(err, &se) && (se.StatusCode == http.StatusUnauthorized || se.StatusCode == http.StatusForbidden)
+}
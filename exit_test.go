@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/wacul/protter/prott"
+)
+
+func TestExitCode(t *testing.T) {
+	netErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	for _, tt := range []struct {
+		err  error
+		want int
+	}{
+		{nil, 0},
+		{usageError{errors.New("unknown flag")}, exitUsage},
+		{uploadErrors{errors.New("failed to upload")}, exitPartialFailure},
+		{uploadErrors{missingProjectError{"App"}}, exitFailure},
+		{uploadErrors{missingProjectError{"App"}, errors.New("failed to upload")}, exitPartialFailure},
+		{prott.ErrInvalidLogin, exitAuthFailure},
+		{fmt.Errorf("invalid API key: %w", prott.ErrSessionRejected), exitAuthFailure},
+		{&prott.StatusError{StatusCode: http.StatusForbidden}, exitAuthFailure},
+		{&prott.StatusError{StatusCode: http.StatusInternalServerError}, exitFailure},
+		{netErr, exitNetworkFailure},
+		{errors.New("other"), exitFailure},
+	} {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRejected(t *testing.T) {
+	if rejected(&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}) {
+		t.Error("a network error is reported as rejected credentials")
+	}
+	if !rejected(&prott.StatusError{StatusCode: http.StatusUnauthorized}) {
+		t.Error("a 401 is not reported as rejected credentials")
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// gitOutput runs git with args in dir and returns its trimmed standard output.
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// gitBranch returns the branch checked out in dir.
+// A detached HEAD, as CI checks out a commit, has no branch: the branch of GitHub Actions is returned instead, or "".
+func gitBranch(dir string) (string, error) {
+	branch, err := gitOutput(dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	if branch == "HEAD" {
+		return githubBranch(), nil
+	}
+	return branch, nil
+}
+
+// githubBranch returns the branch of the GitHub Actions run: the head branch of a pull request,
+// or the branch pushed to. It is "" for a tag or out of GitHub Actions.
+func githubBranch() string {
+	if ref := os.Getenv("GITHUB_HEAD_REF"); ref != "" {
+		return ref
+	}
+	if os.Getenv("GITHUB_REF_TYPE") == "branch" {
+		return os.Getenv("GITHUB_REF_NAME")
+	}
+	return ""
+}
+
+// gitLog returns the last 10 commits in dir, each formatted with the git log --format
+// (or in one line if format is empty).
+func gitLog(dir, format string) (string, error) {
+	args := []string{"log", "-10"}
+	if format == "" {
+		args = append(args, "--oneline")
+	} else {
+		args = append(args, "--format="+format)
+	}
+	return gitOutput(dir, args...)
+}
+
+// githubRunURL returns the URL of the GitHub Actions run protter runs in, or "" outside of it.
+func githubRunURL() string {
+	server, repo, runID := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID")
+	if server == "" || repo == "" || runID == "" {
+		return ""
+	}
+	return strings.TrimSuffix(server, "/") + "/" + repo + "/actions/runs/" + runID
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestGitBranchDetached(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("no git")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"-c", "user.name=protter", "-c", "user.email=protter@example.com", "commit", "-q", "--allow-empty", "-m", "first"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s %s", args, err, out)
+		}
+	}
+	for _, env := range []string{"GITHUB_HEAD_REF", "GITHUB_REF_TYPE", "GITHUB_REF_NAME"} {
+		t.Setenv(env, "")
+	}
+	if branch, err := gitBranch(dir); err != nil || branch != "main" {
+		t.Errorf("on a branch: got %q, %v, want main", branch, err)
+	}
+
+	if out, err := exec.Command("git", "-C", dir, "checkout", "-q", "--detach").CombinedOutput(); err != nil {
+		t.Fatalf("git checkout --detach: %s %s", err, out)
+	}
+	for _, tt := range []struct {
+		headRef, refType, refName string
+		want                      string
+	}{
+		{"", "", "", ""},
+		{"feature", "branch", "123/merge", "feature"},
+		{"", "branch", "main", "main"},
+		{"", "tag", "v1.0.0", ""},
+	} {
+		t.Setenv("GITHUB_HEAD_REF", tt.headRef)
+		t.Setenv("GITHUB_REF_TYPE", tt.refType)
+		t.Setenv("GITHUB_REF_NAME", tt.refName)
+		if branch, err := gitBranch(dir); err != nil || branch != tt.want {
+			t.Errorf("detached with %+v: got %q, %v, want %q", tt, branch, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// subdirectoryModes are the ways --subdirectories takes the directories under a project directory,
+// like Onboarding of .exportedArtboards/App/Onboarding/step1.png.
+// "project" takes them as a part of the project name ("App/Onboarding") as protter always did.
+// "group" uploads the artboards into the screen group of the directories ("Onboarding") of the project "App",
+// creating the group if the project does not have it yet. "tag" tags the screens with the directories instead.
+// Deeper directories are joined with "/", as in the group "Onboarding/Signup".
+var subdirectoryModes = []string{"project", "group", "tag"}
+
+// splitSubdirectory splits the directory of an artboard into the project directory and the subdirectories under it.
+// The subdirectories are empty with --subdirectories=project.
+func (s *syncer) splitSubdirectory(dir string) (string, string) {
+	if s.opts.Subdirectories == "project" {
+		return dir, ""
+	}
+	dir = filepath.ToSlash(dir)
+	if i := strings.Index(dir, "/"); i >= 0 {
+		return dir[:i], dir[i+1:]
+	}
+	return dir, ""
+}
+
+// subdirectory returns the subdirectories under the project directory of the artboard.
+func (s *syncer) subdirectory(path string) string {
+	dir, _, err := parsePath(path)
+	if err != nil {
+		return "" // given to the upload command
+	}
+	_, sub := s.splitSubdirectory(dir)
+	return sub
+}
+
+// claimName records the artboard as the one uploaded to the screen of the name with --subdirectories=tag.
+// The artboards of the same name in other subdirectories would replace the one screen of the name, so it
+// reports false with the subdirectories of both for them. The groups of --subdirectories=group tell the screens apart.
+func (s *syncer) claimName(project Project, name, path string) (string, string, bool) {
+	if s.opts.Subdirectories != "tag" {
+		return "", "", true
+	}
+	sub := s.subdirectory(path)
+	s.namesMu.Lock()
+	defer s.namesMu.Unlock()
+	subs := s.names[project.Name]
+	if subs == nil {
+		subs = map[string]string{}
+		s.names[project.Name] = subs
+	}
+	if other, ok := subs[name]; ok && other != sub {
+		return sub, other, false
+	}
+	subs[name] = sub
+	return sub, "", true
+}
+
+// addSubdirectory sets the group or the tag of the subdirectories of the artboard to the fields of its screen.
+func (s *syncer) addSubdirectory(fields url.Values, project Project, path string) error {
+	sub := s.subdirectory(path)
+	if sub == "" {
+		return nil
+	}
+	switch s.opts.Subdirectories {
+	case "group":
+		id, err := s.groupID(project, sub)
+		if err != nil {
+			return err
+		}
+		fields.Set("screen[group_id]", id)
+	case "tag":
+		fields.Add("screen[tags][]", sub)
+	}
+	return nil
+}
+
+// groupID returns the ID of the screen group of the project, creating the group if it does not exist.
+func (s *syncer) groupID(project Project, name string) (string, error) {
+	// held while creating a group, not to create one twice from the parallel uploads
+	s.groupsMu.Lock()
+	defer s.groupsMu.Unlock()
+	ids, ok := s.groups[project.Name]
+	if !ok {
+		groups, err := s.client.Groups(s.ctx, project.ID)
+		if err != nil {
+			return "", err
+		}
+		ids = make(map[string]string, len(groups))
+		for _, g := range groups {
+			ids[g.Name] = g.ID
+		}
+		s.groups[project.Name] = ids
+	}
+	if id, ok := ids[name]; ok {
+		return id, nil
+	}
+	group, err := s.client.CreateGroup(s.ctx, project.ID, name)
+	if err != nil {
+		return "", err
+	}
+	s.log.event("group_created", logFields{"project": project.Name, "group": name, "id": group.ID}, "created a group %q of a project %q", name, project.Name)
+	ids[name] = group.ID
+	return group.ID, nil
+}
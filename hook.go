@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// projectRun tracks the uploads to a project during a sync for the project hooks and the summary.
+type projectRun struct {
+	project Project
+
+	startOnce sync.Once
+	startErr  error
+
+	mu         sync.Mutex
+	screens    int
+	errors     int
+	skipped    int
+	bytes      int64 // of the uploaded files
+	startedAt  time.Time
+	finishedAt time.Time
+}
+
+// runHook runs the command with sh, adding env to the environment.
+// Its output is written to the standard error, to keep the standard output for the progress messages, which may be JSON.
+func runHook(command string, env ...string) error {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run a hook %q: %s", command, err)
+	}
+	return nil
+}
+
+func (r *projectRun) env() []string {
+	return []string{
+		"PROJECT_NAME=" + r.project.Name,
+		"PROJECT_ID=" + r.project.ID,
+	}
+}
+
+// start calls prepare once, before the first screen of the project is uploaded.
+// The other uploads to the project wait for it.
+func (r *projectRun) start(prepare func(*projectRun) error) error {
+	r.startOnce.Do(func() {
+		r.mu.Lock()
+		r.startedAt = time.Now()
+		r.mu.Unlock()
+		r.startErr = prepare(r)
+	})
+	return r.startErr
+}
+
+func (r *projectRun) done(size int64, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.screens++
+	r.finishedAt = time.Now()
+	if err != nil {
+		r.errors++
+		return
+	}
+	r.bytes += size
+}
+
+// skip counts an artboard of the project not uploaded.
+func (r *projectRun) skip() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.skipped++
+}
+
+// finish runs the --project-finish-hook with the result of the uploads to the project.
+func (r *projectRun) finish(hook string) error {
+	if hook == "" {
+		return nil
+	}
+	r.mu.Lock()
+	env := append(r.env(),
+		"SCREEN_COUNT="+strconv.Itoa(r.screens),
+		"ERROR_COUNT="+strconv.Itoa(r.errors),
+	)
+	r.mu.Unlock()
+	return runHook(hook, env...)
+}
+
+func (s *syncer) projectRun(project Project) *projectRun {
+	s.runsMu.Lock()
+	defer s.runsMu.Unlock()
+	r, ok := s.runs[project.Name]
+	if !ok {
+		r = &projectRun{project: project}
+		s.runs[project.Name] = r
+	} else if r.project.ID == "" {
+		r.project = project // first counted by a skip before the project is resolved
+	}
+	return r
+}
+
+// finishProjects sets the cover screen and runs the --project-finish-hook for each project uploaded to.
+func (s *syncer) finishProjects() error {
+	var names []string
+	for name := range s.runs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if s.runs[name].screens == 0 {
+			continue // all skipped
+		}
+		if s.opts.CoverScreenPattern != "" {
+			project := s.runs[name].project
+			if ok, err := setCoverScreen(s.ctx, s.client, project, s.opts.CoverScreenPattern); err != nil {
+				return err
+			} else if ok {
+				s.log.event("cover_screen_set", logFields{"project": project.Name, "pattern": s.opts.CoverScreenPattern}, "")
+			}
+		}
+		if err := s.runs[name].finish(s.opts.ProjectFinishHook); err != nil {
+			return err
+		}
+	}
+	return nil
+}
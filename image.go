@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+)
+
+func init() {
+	// the standard library has no WebP decoder: read only the dimensions for imageSize
+	image.RegisterFormat("webp", "RIFF????WEBP", decodeWebP, decodeWebPConfig)
+}
+
+// imageSize reads the dimensions of the image file without decoding whole pixels.
+func imageSize(path string) (int, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	conf, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return 0, 0, err
+	}
+	return conf.Width, conf.Height, nil
+}
+
+var errInvalidWebP = errors.New("invalid webp image")
+
+func decodeWebP(r io.Reader) (image.Image, error) {
+	return nil, errors.New("decoding webp images is not supported")
+}
+
+// decodeWebPConfig reads the canvas size from the first chunk of a WebP file
+// (VP8 for lossy, VP8L for lossless or VP8X for extended images).
+func decodeWebPConfig(r io.Reader) (image.Config, error) {
+	var b [30]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return image.Config{}, errInvalidWebP
+	}
+	var width, height int
+	switch string(b[12:16]) {
+	case "VP8 ":
+		if b[23] != 0x9d || b[24] != 0x01 || b[25] != 0x2a {
+			return image.Config{}, errInvalidWebP
+		}
+		width = int(binary.LittleEndian.Uint16(b[26:28]) & 0x3fff)
+		height = int(binary.LittleEndian.Uint16(b[28:30]) & 0x3fff)
+	case "VP8L":
+		if b[20] != 0x2f {
+			return image.Config{}, errInvalidWebP
+		}
+		bits := binary.LittleEndian.Uint32(b[21:25])
+		width = int(bits&0x3fff) + 1
+		height = int(bits>>14&0x3fff) + 1
+	case "VP8X":
+		width = int(uint32(b[24])|uint32(b[25])<<8|uint32(b[26])<<16) + 1
+		height = int(uint32(b[27])|uint32(b[28])<<8|uint32(b[29])<<16) + 1
+	default:
+		return image.Config{}, errInvalidWebP
+	}
+	return image.Config{ColorModel: color.RGBAModel, Width: width, Height: height}, nil
+}
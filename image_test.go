@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// webpHeader returns the first 30 bytes of a WebP file of the chunk, with the chunk data from the byte 20.
+func webpHeader(chunk string, data ...byte) []byte {
+	b := make([]byte, 30)
+	copy(b, "RIFF\x00\x00\x00\x00WEBP"+chunk)
+	copy(b[20:], data)
+	return b
+}
+
+func TestDecodeWebPConfig(t *testing.T) {
+	for _, tt := range []struct {
+		name          string
+		header        []byte
+		width, height int
+	}{
+		// VP8: a frame tag, the start code 9d 01 2a, then the 14-bit width and height
+		{"VP8", webpHeader("VP8 ", 0, 0, 0, 0x9d, 0x01, 0x2a, 0x77, 0x01, 0x36, 0x03), 375, 822},
+		// VP8L: the signature 2f, then the width-1 and height-1 in 14 bits each
+		{"VP8L", webpHeader("VP8L", 0x2f, 0x76, 0x41, 0xcd, 0x00), 375, 822},
+		// VP8X: flags and reserved bytes, then the width-1 and height-1 in 24 bits each
+		{"VP8X", webpHeader("VP8X", 0, 0, 0, 0, 0xdd, 0x05, 0x00, 0x0b, 0x12, 0x00), 1502, 4620},
+	} {
+		config, err := decodeWebPConfig(bytes.NewReader(tt.header))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if config.Width != tt.width || config.Height != tt.height {
+			t.Errorf("%s: got %dx%d, want %dx%d", tt.name, config.Width, config.Height, tt.width, tt.height)
+		}
+	}
+}
+
+func TestDecodeWebPConfigInvalid(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		header []byte
+	}{
+		{"short", []byte("RIFF")},
+		{"unknown chunk", webpHeader("ALPH")},
+		{"VP8 without the start code", webpHeader("VP8 ", 0, 0, 0, 0, 0, 0, 0x77, 0x01, 0x36, 0x03)},
+		{"VP8L without the signature", webpHeader("VP8L", 0, 0x76, 0x41, 0xcd, 0x00)},
+	} {
+		if _, err := decodeWebPConfig(bytes.NewReader(tt.header)); err != errInvalidWebP {
+			t.Errorf("%s: got %v, want errInvalidWebP", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/wacul/protter/prott"
+)
+
+// gestures is the content of <screen>.gestures.json, naming the target screen of each gesture.
+type gestures struct {
+	SwipeLeft  string `json:"swipe_left"`
+	SwipeRight string `json:"swipe_right"`
+	SwipeUp    string `json:"swipe_up"`
+	SwipeDown  string `json:"swipe_down"`
+	DoubleTap  string `json:"double_tap"`
+	LongPress  string `json:"long_press"`
+
+	ForceTouch      string `json:"force_touch"`
+	ForceTouchStyle string `json:"force_touch_style"` // sent with the upload
+}
+
+var forceTouchStyles = []string{"peek", "pop", "action_sheet"}
+
+// screenLink is an uploaded screen whose fields refer to other screens by name.
+// They are resolved to screen IDs once all screens are uploaded.
+type screenLink struct {
+	project Project
+	screen  Screen
+	targets map[string]string // field -> target screen name
+}
+
+// links reads the fields of the screen referring to other screens by name.
+func (o *artboardOptions) links(path string) (map[string]string, error) {
+	targets := map[string]string{}
+	if o.Gestures {
+		var g gestures
+		if _, err := loadJSON(sidecarPath(path, ".gestures.json"), &g); err != nil {
+			return nil, err
+		}
+		setTarget(targets, "screen[swipe_left_target]", g.SwipeLeft)
+		setTarget(targets, "screen[swipe_right_target]", g.SwipeRight)
+		setTarget(targets, "screen[swipe_up_target]", g.SwipeUp)
+		setTarget(targets, "screen[swipe_down_target]", g.SwipeDown)
+		setTarget(targets, "screen[double_tap_target]", g.DoubleTap)
+		setTarget(targets, "screen[long_press_target]", g.LongPress)
+		setTarget(targets, "screen[force_touch_target]", g.ForceTouch)
+	}
+	if o.PullToRefresh {
+		var conf pullToRefreshConfig
+		if _, err := loadJSON(sidecarPath(path, ".pull_to_refresh.json"), &conf); err != nil {
+			return nil, err
+		}
+		setTarget(targets, "screen[pull_to_refresh_target]", conf.Target)
+	}
+	if o.BackGesture {
+		var conf navigationConfig
+		if _, err := loadJSON(sidecarPath(path, ".navigation.json"), &conf); err != nil {
+			return nil, err
+		}
+		setTarget(targets, "screen[back_gesture_target]", conf.BackGestureTarget)
+	}
+	return targets, nil
+}
+
+func setTarget(targets map[string]string, field, name string) {
+	if name != "" {
+		targets[field] = name
+	}
+}
+
+func (s *syncer) addLink(project Project, screen Screen, path string) error {
+	targets, err := s.artboard.links(path)
+	if err != nil || len(targets) == 0 {
+		return err
+	}
+	s.linksMu.Lock()
+	defer s.linksMu.Unlock()
+	s.links = append(s.links, screenLink{project: project, screen: screen, targets: targets})
+	return nil
+}
+
+// uploaded records the ID of an uploaded screen to resolve links to it.
+// A name of screens in different groups is recorded as ambiguous.
+func (s *syncer) uploaded(project Project, screen Screen, name string) {
+	s.linksMu.Lock()
+	defer s.linksMu.Unlock()
+	if s.screenIDs[project.Name] == nil {
+		s.screenIDs[project.Name] = map[string]string{}
+	}
+	addScreenID(s.screenIDs[project.Name], name, screen.ID)
+}
+
+// addScreenID records the screen ID by the name, or "" for a name of several screens.
+func addScreenID(ids map[string]string, name, id string) {
+	if other, ok := ids[name]; ok && other != id {
+		id = ""
+	}
+	ids[name] = id
+}
+
+// resolveLinks updates the linking screens with the IDs of their target screens.
+// Targets not uploaded in this run are looked up in the screens of the project on Prott.
+func (s *syncer) resolveLinks() error {
+	fetched := map[string]bool{}
+	for _, l := range s.links {
+		ids := s.screenIDs[l.project.Name]
+		if ids == nil {
+			ids = map[string]string{}
+			s.screenIDs[l.project.Name] = ids
+		}
+		fields := url.Values{}
+		for field, name := range l.targets {
+			if _, ok := ids[name]; !ok && !fetched[l.project.Name] {
+				fetched[l.project.Name] = true
+				if err := fetchScreenIDs(s.ctx, s.client, l.project, ids); err != nil {
+					return err
+				}
+			}
+			id, ok := ids[name]
+			if !ok {
+				s.log.warn("link_missing", logFields{"project": l.project.Name, "screen": l.screen.Name, "target": name}, "a screen %q linked from %q is not exist", name, l.screen.Name)
+				continue
+			}
+			if id == "" {
+				s.log.warn("link_ambiguous", logFields{"project": l.project.Name, "screen": l.screen.Name, "target": name}, "a screen %q linked from %q is not linked: several screens have the name", name, l.screen.Name)
+				continue
+			}
+			fields.Set(field, id)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if err := s.client.UpdateScreen(s.ctx, l.screen, fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchScreenIDs adds the screens of the project on Prott missing in ids.
+func fetchScreenIDs(ctx context.Context, client *prott.Client, project Project, ids map[string]string) error {
+	remote, err := getScreenList(ctx, client, project.ID, true)
+	if err != nil {
+		return err
+	}
+	fetched := map[string]string{}
+	for key, id := range remote {
+		addScreenID(fetched, key.Name, id)
+	}
+	for name, id := range fetched {
+		if _, ok := ids[name]; !ok {
+			ids[name] = id
+		}
+	}
+	return nil
+}
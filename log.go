@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// logFields are the attributes of a logged event.
+type logFields map[string]interface{}
+
+// logLevel is how much of the events is printed in the text format.
+type logLevel int
+
+const (
+	logQuiet   logLevel = iota // only warnings
+	logNormal                  // also the messages of the events
+	logVerbose                 // also the fields of the events without a message
+)
+
+// logger writes the events of a run as text, or as newline-delimited JSON
+// objects with --output-format=json, e.g.
+// {"event":"upload_result","project":"MyApp","screen":"Home","status":200,"path":"...","error":null}
+// All the events are written in JSON regardless of the level.
+type logger struct {
+	mu    sync.Mutex
+	w     io.Writer
+	json  bool
+	level logLevel
+}
+
+func newLogger(w io.Writer, format string, level logLevel) *logger {
+	return &logger{w: w, json: format == "json", level: level}
+}
+
+// event logs an event named name with the fields.
+// In the text format, the message formatted with args is written instead (nothing if format is empty,
+// unless --verbose writes the fields).
+func (l *logger) event(name string, fields logFields, format string, args ...interface{}) {
+	l.log(logNormal, name, fields, format, args...)
+}
+
+// warn logs an event like event, which is written even with --quiet.
+func (l *logger) warn(name string, fields logFields, format string, args ...interface{}) {
+	l.log(logQuiet, name, fields, format, args...)
+}
+
+func (l *logger) log(level logLevel, name string, fields logFields, format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.json {
+		switch {
+		case l.level < level:
+		case format != "":
+			fmt.Fprintf(l.w, format+"\n", args...)
+		case l.level >= logVerbose:
+			fmt.Fprintln(l.w, formatFields(name, fields))
+		}
+		return
+	}
+	obj := make(map[string]interface{}, len(fields)+1)
+	for k, v := range fields {
+		obj[k] = v
+	}
+	obj["event"] = name
+	if err := json.NewEncoder(l.w).Encode(obj); err != nil {
+		fmt.Fprintf(l.w, "{\"event\":%q,\"error\":%q}\n", name, err.Error())
+	}
+}
+
+// formatFields formats an event as "name key=value ..." with the keys sorted.
+func formatFields(name string, fields logFields) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := []string{name}
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, fields[k]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// errorField returns the message of err for the "error" field, or nil if err is nil.
+func errorField(err error) interface{} {
+	if err == nil {
+		return nil
+	}
+	return err.Error()
+}
@@ -1,170 +1,546 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io"
-	"mime/multipart"
 	"net/http"
 	"net/http/cookiejar"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
 
 	"github.com/alecthomas/kingpin"
-	"github.com/kyoh86/fastwalk"
+	"github.com/wacul/protter/prott"
 	"golang.org/x/net/publicsuffix"
+	"golang.org/x/text/language"
 )
 
-type Project struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
-}
+// Project and Screen are the resources of Prott protter deals with.
+type (
+	Project = prott.Project
+	Screen  = prott.Screen
+)
 
 func main() {
+	err := run()
+	if errs, ok := err.(uploadErrors); ok {
+		for _, err := range errs {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		fmt.Fprintf(os.Stderr, "failed to sync with %d errors\n", len(errs))
+	} else if _, ok := err.(usageError); ok {
+		fmt.Fprintf(os.Stderr, "protter: error: %s, try --help\n", err)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "protter: error: %s\n", err)
+	}
+	os.Exit(exitCode(err))
+}
+
+// run runs the command given by the arguments.
+func run() error {
 	app := kingpin.New("protter", "upload exported sketch artboards to prott")
 
 	var flags struct {
-		// CookieFile string
-		CWD           string
+		CookieFile    string
+		CWDs          []string
 		ProttEmail    string
 		ProttPassword string
+		APIKey        string
+		Keychain      bool
+
+		passwordFlag bool // --prott-password is on the command line, not from PROTT_PASSWORD
+
+		ProjectListFile string
+		OutputFormat    string
+		ConfigFile      string
+
+		Retry      retryPolicy
+		Connection connectionOptions
+
+		Verbose bool
+		Quiet   bool
 	}
-	// app.Flag("cookie-file", "filepath to save / restore a login session").Default("cookie.jar").StringVar(&flags.CookieFile)
-	app.Flag("current-directory", "Run as if git was started in <path> instead of the current working directory.").Default(".").Short('C').PlaceHolder("<path>").ExistingDirVar(&flags.CWD)
+	app.Flag("cookie-file", "filepath to save / restore a login session (empty to disable)").Default("~/.protter/session.jar").PlaceHolder("<path>").StringVar(&flags.CookieFile)
+	app.Flag("current-directory", "Run as if git was started in <path> instead of the current working directory.").Default(".").Short('C').PlaceHolder("<path>").ExistingDirsVar(&flags.CWDs)
 	app.Flag("prott-email", "an email of the account of the Prott.app").Envar("PROTT_EMAIL").StringVar(&flags.ProttEmail)
-	app.Flag("prott-password", "a password of the account of the Prott.app").Envar("PROTT_PASSWORD").StringVar(&flags.ProttPassword)
+	app.Flag("prott-password", "a password of the account of the Prott.app (asked on a terminal if not given; avoid it on the command line, where the shell history keeps it)").Envar("PROTT_PASSWORD").Action(func(*kingpin.ParseContext) error {
+		flags.passwordFlag = true
+		return nil
+	}).StringVar(&flags.ProttPassword)
+	app.Flag("project-api-key", "an API key to authenticate with instead of the email and password").Envar("PROTT_API_KEY").PlaceHolder("<key>").StringVar(&flags.APIKey)
+	app.Flag("api-token", "an alias of --project-api-key").Envar("PROTT_API_TOKEN").PlaceHolder("<key>").StringVar(&flags.APIKey)
+	app.Flag("keychain", "read the password or the API key saved by login --keychain from the OS credential store (the macOS Keychain, or the Secret Service by secret-tool)").BoolVar(&flags.Keychain)
+	app.Flag("output-format", "a format of the progress messages (text or json: one JSON object per line)").Default("text").EnumVar(&flags.OutputFormat, "text", "json")
+	app.Flag("verbose", "also print the details of each request and upload in the text format").Short('v').BoolVar(&flags.Verbose)
+	app.Flag("quiet", "print only warnings and failures in the text format").Short('q').BoolVar(&flags.Quiet)
+	app.Flag("project-list-file", "a JSON file to save the fetched projects to, and to read them from with --dry-run").PlaceHolder("<path>").StringVar(&flags.ProjectListFile)
+	app.Flag("config", "a JSON file mapping directories under .exportedArtboards to Prott projects (default: .protter.json in --current-directory)").PlaceHolder("<path>").StringVar(&flags.ConfigFile)
+
+	var artboard artboardOptions
+	app.Flag("artboard-export-artboard-id", "send the Sketch artboard UUIDs found in manifest.json beside the artboards").BoolVar(&artboard.ArtboardID)
+	app.Flag("artboard-manifest-file", "a manifest mapping artboard file names to Sketch artboard UUIDs (default: manifest.json beside the artboards)").PlaceHolder("<path>").ExistingFileVar(&artboard.Manifest)
+	app.Flag("sketch-file", "a .sketch document to read the artboard IDs of the exported artboards from, to replace the right screens after renames").PlaceHolder("<path>").ExistingFileVar(&artboard.SketchFile)
+	app.Flag("artboard-branch", "a branch name to tag uploaded screens with (default: the current git branch, or the branch of GitHub Actions on a detached HEAD)").PlaceHolder("<name>").StringVar(&artboard.Branch)
+	app.Flag("artboard-commit", "a commit hash to record in uploaded screens (default: the current git commit)").PlaceHolder("<sha>").StringVar(&artboard.Commit)
+	app.Flag("upload-session-id", "an identifier grouping the screens uploaded in this run (default: a random UUID)").PlaceHolder("<uuid>").StringVar(&artboard.SessionID)
+	app.Flag("upload-artifact-url", "a URL of the CI run or artifact to link the uploads to (default: the GitHub Actions run)").PlaceHolder("<url>").StringVar(&artboard.ArtifactURL)
+	app.Flag("artboard-required-fonts-file", "read fonts required by each screen from <screen>.required_fonts.txt (one font per line)").BoolVar(&artboard.RequiredFonts)
+	app.Flag("artboard-color-swatch-file", "attach the color palette of each screen from <screen>.colors.json").BoolVar(&artboard.ColorSwatch)
+	app.Flag("artboard-spacing-file", "attach the spacing tokens of each screen from <screen>.spacing.json").BoolVar(&artboard.Spacing)
+	app.Flag("artboard-icon-set-file", "attach the icons referenced by each screen from <screen>.icons.json").BoolVar(&artboard.IconSet)
+	app.Flag("artboard-shadow-file", "attach the shadow tokens of each screen from <screen>.shadows.json").BoolVar(&artboard.Shadow)
+	app.Flag("artboard-motion-file", "attach the transition animation of each screen from <screen>.motion.json").BoolVar(&artboard.Motion)
+	app.Flag("artboard-prototype-gesture", "link screens by gestures (swipes, double tap, long press and force touch) from <screen>.gestures.json").BoolVar(&artboard.Gestures)
+	app.Flag("artboard-border-radius", "a corner radius in pixels to display screens with (overridden by border_radius.json beside the artboards)").PlaceHolder("N").SetValue(&artboard.BorderRadius)
+	app.Flag("artboard-safe-area", "safe area insets in pixels of screens (overridden by safe_areas.json beside the artboards)").PlaceHolder("<top>,<bottom>,<left>,<right>").SetValue(&artboard.SafeArea)
+	app.Flag("artboard-prototype-device-id", "a Prott prototype device of screens (e.g. iphone14_pro)").PlaceHolder("<id>").StringVar(&artboard.PrototypeDevice)
+	app.Flag("artboard-prototype-device-map", "a JSON file mapping screen names to Prott prototype devices").PlaceHolder("<file>").ExistingFileVar(&artboard.PrototypeDeviceMap)
+	app.Flag("artboard-status-bar-style", "a status bar style of screens (light, dark or hidden)").Default("dark").EnumVar(&artboard.StatusBarStyle, statusBarStyles...)
+	app.Flag("status-bar-style-map", "a JSON file mapping screen names to status bar styles").PlaceHolder("<json>").ExistingFileVar(&artboard.StatusBarStyleMap)
+	app.Flag("status-bar-height", "a status bar height in pixels of screens").PlaceHolder("N").SetValue(&artboard.StatusBarHeight)
+	app.Flag("artboard-prototype-nav-bar", "show the navigation bar of the prototype player on screens").SetValue(&artboard.NavBar)
+	app.Flag("nav-bar-config-file", "a JSON file mapping screen names to navigation bar configurations ({\"show\": bool, \"title\": string})").PlaceHolder("<json>").ExistingFileVar(&artboard.NavBarConfig)
+	app.Flag("artboard-prototype-tab-bar", "show the tab bar of the prototype player on screens").SetValue(&artboard.TabBar)
+	app.Flag("tab-bar-config-file", "a JSON file mapping screen names to tab bar configurations ({\"show\": bool, \"items\": [string]})").PlaceHolder("<json>").ExistingFileVar(&artboard.TabBarConfig)
+	app.Flag("scrollable", "make screens scrollable along the longer side of the artboard").BoolVar(&artboard.Scrollable)
+	app.Flag("artboard-prototype-scroll-direction", "a scroll axis of screens (vertical, horizontal, both or none)").EnumVar(&artboard.ScrollDirection, scrollDirections...)
+	app.Flag("scroll-direction-map", "a JSON file mapping screen names to scroll axes").PlaceHolder("<json>").ExistingFileVar(&artboard.ScrollDirectionMap)
+	app.Flag("artboard-prototype-overflow", "scroll the content outside the artboard instead of clipping it (--no-artboard-prototype-overflow to clip)").SetValue(&artboard.Overflow)
+	app.Flag("overflow-direction", "an axis to scroll the overflowing content along (vertical, horizontal or both; default: the scroll direction)").EnumVar(&artboard.OverflowDirection, "vertical", "horizontal", "both")
+	app.Flag("artboard-device-pixel-ratio", "a display density of screens (default: detected from the @Nx suffix of the file name)").PlaceHolder("<ratio>").FloatVar(&artboard.DevicePixelRatio)
+	app.Flag("scale-suffix-map", "a JSON file mapping file name suffixes to scales (e.g. {\"@2x\": 2.0, \"_hd\": 2.0}), stripped from screen names").PlaceHolder("<json>").ExistingFileVar(&artboard.ScaleSuffixMap)
+	app.Flag("artboard-prototype-home-indicator", "show the iOS home indicator in the prototype player (--no-artboard-prototype-home-indicator to hide it)").Default("true").BoolVar(&artboard.HomeIndicator)
+	app.Flag("home-indicator-map", "a JSON file mapping screen names to whether to hide the iOS home indicator").PlaceHolder("<json>").ExistingFileVar(&artboard.HomeIndicatorMap)
+	app.Flag("artboard-prototype-keyboard", "configure the on-screen keyboard of screens from <screen>.keyboard.json").BoolVar(&artboard.Keyboard)
+	app.Flag("artboard-prototype-pull-to-refresh", "configure pull-to-refresh of screens from <screen>.pull_to_refresh.json").BoolVar(&artboard.PullToRefresh)
+	app.Flag("artboard-prototype-back-gesture", "configure the swipe-from-left-edge back gesture of screens from <screen>.navigation.json").BoolVar(&artboard.BackGesture)
+	app.Flag("artboard-prototype-haptic-feedback", "configure the haptic feedback on the transitions of screens from <screen>.haptics.json ({\"type\": string})").BoolVar(&artboard.HapticFeedback)
+	app.Flag("artboard-prototype-slide-show", "advance screens automatically after the interval in <screen>.timing.json ({\"interval_ms\": int})").BoolVar(&artboard.SlideShow)
+	app.Flag("slide-show-interval", "an interval to advance every screen automatically after (overridden by <screen>.timing.json)").PlaceHolder("<duration>").DurationVar(&artboard.SlideShowInterval)
+	app.Flag("artboard-prototype-popover", "configure the presentation style of screens from <screen>.presentation.json").BoolVar(&artboard.Presentation)
+	app.Flag("artboard-prototype-loading-placeholder", "send <screen>.loading.png beside each artboard as its loading placeholder").BoolVar(&artboard.LoadingPlaceholders)
+	app.Flag("loading-placeholder", "a PNG to send as the loading placeholder of every screen").PlaceHolder("<path>").ExistingFileVar(&artboard.LoadingPlaceholder)
+	app.Flag("enable-pinch-zoom", "enable pinch-to-zoom of screens in the prototype player (overridden by pinch_zoom.json beside the artboards)").SetValue(&artboard.PinchZoom)
+	app.Flag("artboard-prototype-tap-highlight", "highlight hotspots tapped in the prototype player").SetValue(&artboard.TapHighlight)
+	app.Flag("tap-highlight-color", "a color of the tap highlight of screens (enables the highlight)").PlaceHolder("<hex>").Action(func(*kingpin.ParseContext) error {
+		return validateHexColor(artboard.TapHighlightColor)
+	}).StringVar(&artboard.TapHighlightColor)
+	app.Flag("tap-highlight-map", "a JSON file mapping screen names to tap highlights ({\"enabled\": bool, \"color\": string})").PlaceHolder("<json>").ExistingFileVar(&artboard.TapHighlightMap)
+	app.Flag("artboard-orientation", "an orientation of screens (portrait or landscape; default: detected from the image size)").EnumVar(&artboard.Orientation, "portrait", "landscape")
+	app.Flag("orientation-lock", "lock the orientation of screens in the prototype player (portrait, landscape or none)").EnumVar(&artboard.OrientationLock, orientationLocks...)
+	app.Flag("orientation-lock-map", "a JSON file mapping screen names to orientation locks").PlaceHolder("<json>").ExistingFileVar(&artboard.OrientationLockMap)
+
+	var newProject projectOptions
+	var projectNotify string
+	app.Flag("project-privacy", "a visibility of created projects (public, private or team)").Default("private").EnumVar(&newProject.Privacy, "public", "private", "team")
+	app.Flag("project-device-id", "a Prott prototype device of created projects (default: --artboard-prototype-device-id)").PlaceHolder("<id>").StringVar(&newProject.Device)
+	app.Flag("project-orientation", "an orientation of created projects (portrait or landscape; default: --artboard-orientation)").EnumVar(&newProject.Orientation, "portrait", "landscape")
+	app.Flag("project-team", "a name of the team to place created projects in").PlaceHolder("<team-name>").StringVar(&newProject.Team)
+	app.Flag("project-url-slug", "a URL slug of created projects (alphanumeric characters and hyphens)").PlaceHolder("<slug>").Action(func(*kingpin.ParseContext) error {
+		return validateSlug(newProject.Slug)
+	}).StringVar(&newProject.Slug)
+	app.Flag("project-notify-on-create", "comma separated emails to invite to created projects").PlaceHolder("<emails>").Action(func(*kingpin.ParseContext) error {
+		for _, email := range strings.Split(projectNotify, ",") {
+			if email = strings.TrimSpace(email); email != "" {
+				newProject.NotifyOnCreate = append(newProject.NotifyOnCreate, email)
+			}
+		}
+		return nil
+	}).StringVar(&projectNotify)
+	app.Flag("project-member-role", "a role of the members invited by --project-notify-on-create (owner, editor or viewer)").Default("viewer").EnumVar(&newProject.MemberRole, "owner", "editor", "viewer")
+	app.Flag("artboard-prototype-splash", "an image to upload as the splash screen shown before the first screen of each created project").PlaceHolder("<path>").ExistingFileVar(&newProject.Splash)
+	app.Flag("project-description-from-git-log", "describe created projects with the last 10 commits of the --current-directory").BoolVar(&newProject.DescriptionLog)
+	app.Flag("git-log-format", "a git log --format of the commits in --project-description-from-git-log (default: --oneline)").PlaceHolder("<template>").StringVar(&newProject.DescriptionTmpl)
+	app.Flag("project-tag", "a tag to assign to projects (repeatable)").PlaceHolder("<value>").StringsVar(&newProject.Tags)
+	app.Flag("project-tag-file", "a JSON file mapping project names to their tags").PlaceHolder("<json>").ExistingFileVar(&newProject.TagFile)
+	app.Flag("project-language", "a primary language (BCP 47 code, e.g. en or ja) of the projects created or uploaded to").PlaceHolder("<code>").Action(func(*kingpin.ParseContext) error {
+		_, err := language.Parse(newProject.Language)
+		return err
+	}).StringVar(&newProject.Language)
 
-	if _, err := app.Parse(os.Args[1:]); err != nil {
-		panic(err)
+	var syncFlags syncOptions
+	syncCmd := app.Command("sync", "upload exported artboards found under the current directory").Default()
+	syncCmd.Flag("project", "a name of the project to upload to, skipping the other projects (repeatable)").Short('p').PlaceHolder("<name>").StringsVar(&syncFlags.Projects)
+	syncCmd.Flag("output", "an alias of --output-format").PlaceHolder("<format>").EnumVar(&flags.OutputFormat, "text", "json")
+	var exportFlags struct {
+		Enabled  bool
+		Exporter string
 	}
+	syncCmd.Flag("sketch-export", "export the artboards of --sketch-file with --sketch-exporter into a temporary directory and upload them to the project named after the file (or the --project), instead of walking --current-directory").BoolVar(&exportFlags.Enabled)
+	syncCmd.Flag("sketch-exporter", "a command exporting the artboards of $SKETCH_FILE into $EXPORT_DIR, run by sh").Default(defaultSketchExporter).PlaceHolder("<command>").StringVar(&exportFlags.Exporter)
+	app.Flag("dry-run", "print the screens which would be uploaded, signing in to list the projects but sending no changes (offline with --project-list-file)").Short('n').BoolVar(&syncFlags.DryRun)
+	app.Flag("dry-run-confirm", "print the screens which would be uploaded and ask to proceed before uploading them").BoolVar(&syncFlags.DryRunConfirm)
+	app.Flag("yes", "proceed without asking with --dry-run-confirm (required without a terminal)").Short('y').BoolVar(&syncFlags.Yes)
+	app.Flag("skip-existing-projects", "skip projects which already have screens on Prott").BoolVar(&syncFlags.SkipExistingProjects)
+	app.Flag("create-missing-projects", "create the projects not exist on Prott instead of skipping their artboards").BoolVar(&syncFlags.CreateMissing)
+	app.Flag("create-projects", "an alias of --create-missing-projects").Hidden().BoolVar(&syncFlags.CreateMissing)
+	app.Flag("min-existing-screens", "a number of screens a project needs to be skipped by --skip-existing-projects").Default("1").PlaceHolder("N").IntVar(&syncFlags.MinExistingScreens)
+	app.Flag("force-recreate", "always create new screens instead of updating the screens of the same names").BoolVar(&syncFlags.ForceRecreate)
+	app.Flag("state-file", "a file recording the uploaded artboards to tell unchanged ones (relative to the first --current-directory)").Default(".protter/state.json").PlaceHolder("<path>").StringVar(&syncFlags.StateFile)
+	app.Flag("subdirectories", "take the directories under a project directory as a part of the project name (project), the screen group (group) or a tag of the screens (tag)").Default("project").EnumVar(&syncFlags.Subdirectories, subdirectoryModes...)
+	app.Flag("dedup-strategy", "skip artboards unchanged since the last upload by comparing nothing (none), the modification time and size (mtime) or the SHA-256 hash (sha256) in --state-file").Default("none").EnumVar(&syncFlags.DedupStrategy, dedupStrategies...)
+	app.Flag("skip-unchanged-mtime", "same as --dedup-strategy=mtime: fast, but misses files overwritten with the same modification time and size").BoolVar(&syncFlags.SkipUnchangedMtime)
+	app.Flag("incremental", "upload only new and changed artboards, updating their screens (same as --dedup-strategy=sha256)").BoolVar(&syncFlags.Incremental)
+	app.Flag("prune", "delete the screens whose files recorded in --state-file were removed").BoolVar(&syncFlags.Prune)
+	app.Flag("watch", "keep running after the sync, and upload artboards as Sketch exports them until interrupted").BoolVar(&syncFlags.Watch)
+	app.Flag("watch-debounce", "how long an artboard must stay unchanged after a write to be uploaded in --watch").Default("500ms").DurationVar(&syncFlags.WatchDebounce)
+	app.Flag("parallel-walk", "scan multiple --current-directory concurrently").BoolVar(&syncFlags.ParallelWalk)
+	app.Flag("parallelism", "a number of screens uploaded at the same time").Default("4").Short('j').PlaceHolder("N").IntVar(&syncFlags.Concurrency)
+	app.Flag("concurrency", "an alias of --parallelism").Hidden().PlaceHolder("N").IntVar(&syncFlags.Concurrency)
+	app.Flag("parallel", "an alias of --parallelism").Hidden().PlaceHolder("N").IntVar(&syncFlags.Concurrency)
+	app.Flag("retry-count", "a number of times to retry a request failed with a network error or HTTP 429 or 5xx").Default("3").PlaceHolder("N").IntVar(&flags.Retry.Count)
+	app.Flag("retry-max-delay", "the longest wait between the retries of a request (doubling from 1s, unless Retry-After asks for longer)").Default("30s").PlaceHolder("<duration>").DurationVar(&flags.Retry.MaxDelay)
+	app.Flag("api-base", "the base URL of the Prott API, e.g. of an API gateway in front of it (the proxy is taken from HTTPS_PROXY and NO_PROXY)").Envar("PROTT_API_BASE").Default(prott.DefaultBaseURL).PlaceHolder("<url>").StringVar(&flags.Connection.APIBase)
+	app.Flag("ca-bundle", "a PEM file of the CA certificates to trust in addition to the system ones, e.g. of a TLS intercepting proxy").PlaceHolder("<path>").ExistingFileVar(&flags.Connection.CABundle)
+	app.Flag("connect-timeout", "a timeout of connecting to Prott or the proxy (0 for the default of 30s)").Default("0").PlaceHolder("<duration>").DurationVar(&flags.Connection.ConnectTimeout)
+	app.Flag("tls-handshake-timeout", "a timeout of the TLS handshake (0 for the default of 10s)").Default("0").PlaceHolder("<duration>").DurationVar(&flags.Connection.TLSTimeout)
+	app.Flag("request-timeout", "a timeout of each attempt of a request to Prott (0 for none)").Default("0").PlaceHolder("<duration>").DurationVar(&flags.Retry.Timeout)
+	app.Flag("ramp-start", "a number of upload workers to start with (default: --parallelism)").PlaceHolder("N").IntVar(&syncFlags.RampStart)
+	app.Flag("ramp-interval", "an interval to add an upload worker at until --parallelism is reached").PlaceHolder("<duration>").DurationVar(&syncFlags.RampInterval)
+	app.Flag("upload-concurrency-by-size", "upload smaller files first").BoolVar(&syncFlags.BySize)
+	app.Flag("upload-concurrency-by-size-threshold", "prioritize only files smaller than this size with --upload-concurrency-by-size").PlaceHolder("<bytes>").BytesVar(&syncFlags.BySizeThreshold)
+	app.Flag("upload-after", "upload only files modified after the datetime (ISO 8601)").PlaceHolder("<datetime>").SetValue(&syncFlags.UploadAfter)
+	app.Flag("upload-before", "upload only files modified before the datetime (ISO 8601)").PlaceHolder("<datetime>").SetValue(&syncFlags.UploadBefore)
+	app.Flag("project-start-hook", "a command run before the first screen of each project is uploaded (with PROJECT_NAME and PROJECT_ID)").PlaceHolder("<cmd>").StringVar(&syncFlags.ProjectStartHook)
+	app.Flag("project-cover-screen-pattern", "a glob pattern of screen names (e.g. Home*): the first matching screen becomes the cover of the project").PlaceHolder("<glob>").Action(func(*kingpin.ParseContext) error {
+		if _, err := path.Match(syncFlags.CoverScreenPattern, ""); err != nil {
+			return fmt.Errorf("invalid --project-cover-screen-pattern %q: %s", syncFlags.CoverScreenPattern, err)
+		}
+		return nil
+	}).StringVar(&syncFlags.CoverScreenPattern)
+	app.Flag("project-finish-hook", "a command run after the screens of each project are uploaded (with PROJECT_NAME, PROJECT_ID, SCREEN_COUNT and ERROR_COUNT)").PlaceHolder("<cmd>").StringVar(&syncFlags.ProjectFinishHook)
+	app.Flag("upload-progress-file", "a JSON file to keep updated with the progress of the uploads").PlaceHolder("<path>").StringVar(&syncFlags.ProgressFile)
+	app.Flag("upload-chunk-size", "upload files larger than this size in chunks of this size (0 to disable)").Default("0").PlaceHolder("<bytes>").BytesVar(&syncFlags.ChunkSize)
+	app.Flag("upload-group-by-project", "show the uploads, skips, failures, bytes and duration of each project in the summary").BoolVar(&syncFlags.GroupByProject)
+	app.Flag("upload-stats-interval", "an interval to log the upload throughput at (0 to disable)").Default("30s").PlaceHolder("<duration>").DurationVar(&syncFlags.StatsInterval)
+	app.Flag("export-screen-metadata-to-json", "write <screen>.protter_meta.json with the Prott metadata beside each uploaded artboard").BoolVar(&syncFlags.ExportMetadata)
+	app.Flag("metadata-output-dir", "a directory to write the metadata files of --export-screen-metadata-to-json in, in the subdirectories of the artboards per project").PlaceHolder("<dir>").StringVar(&syncFlags.MetadataDir)
+	app.Flag("upload-retry-file", "a JSON file to record failed uploads in, to upload them again with --retry-from (the sync still fails)").PlaceHolder("<path>").StringVar(&syncFlags.RetryFile)
+	app.Flag("retry-from", "upload only the failed uploads recorded in the file by --upload-retry-file").PlaceHolder("<file>").ExistingFileVar(&syncFlags.RetryFrom)
 
-	client, err := buildClient()
-	if err != nil {
-		panic(err)
+	var inspectFlags struct {
+		Project string
+		Screen  string
+		Output  string
 	}
+	screenCmd := app.Command("screen", "manage screens of a project")
+	screenInspectCmd := screenCmd.Command("inspect", "show full metadata for a screen")
+	screenInspectCmd.Flag("project", "a name of the project the screen belongs to").Required().StringVar(&inspectFlags.Project)
+	screenInspectCmd.Flag("output", "an output format (text or json)").Default("text").EnumVar(&inspectFlags.Output, "text", "json")
+	screenInspectCmd.Arg("screen-name", "a name of the screen to inspect").Required().StringVar(&inspectFlags.Screen)
 
-	// login
-	if err := loginPrott(client, flags.ProttEmail, flags.ProttPassword); err != nil {
-		panic(err)
+	var uploadFlags struct {
+		Project string
 	}
+	uploadCmd := app.Command("upload", "upload the artboard images to a project, wherever they are")
+	uploadCmd.Flag("project", "a name of the project to upload to").Short('p').Required().PlaceHolder("<name>").StringVar(&uploadFlags.Project)
+	uploadCmd.Flag("output", "an alias of --output-format").PlaceHolder("<format>").EnumVar(&flags.OutputFormat, "text", "json")
+	uploadCmd.Arg("path", "artboard images (png, jpg or webp) named after their screens").Required().ExistingFilesVar(&syncFlags.Files)
 
-	// get projects list
-	projectList, err := getProjectList(client)
-	if err != nil {
-		panic(err)
-	}
-	projects := map[string]Project{}
-	for _, p := range projectList {
-		projects[p.Name] = p
-		fmt.Println(p.Name)
-	}
-
-	if err := fastwalk.FastWalk(flags.CWD, func(path string, typ os.FileMode) error {
-		projectName, screenName, err := parsePath(path)
-		switch err {
-		case nil:
-			// noop
-		case errInvalidPath:
-			return nil
-		default:
-			return err
-		}
-		project, ok := projects[projectName]
-		if !ok {
-			fmt.Printf("a project %q is not exist\n", projectName)
-			return nil // skip
-		}
+	var pullFlags struct {
+		Project string
+		Dir     string
+	}
+	pullCmd := app.Command("pull", "download the images of the screens of a project, named by the screen names")
+	pullCmd.Flag("project", "a name of the project to download from").Short('p').Required().PlaceHolder("<name>").StringVar(&pullFlags.Project)
+	pullCmd.Arg("dir", "a directory to download into, with a subdirectory for each screen group").Required().StringVar(&pullFlags.Dir)
 
-		return uploadScreen(client, project, screenName, path)
+	loginCmd := app.Command("login", "sign in with --prott-email and --prott-password (asked if not given), and save the session to --cookie-file; with --keychain, also save the password or the API key to the OS credential store")
 
-	}); err != nil {
-		panic(err)
+	var projectsFlags struct {
+		Output string
 	}
-}
+	projectsCmd := app.Command("projects", "manage projects of the Prott.app")
+	projectsListCmd := projectsCmd.Command("list", "show the projects of the account")
+	projectsListCmd.Flag("output", "an output format (text or json)").Default("text").EnumVar(&projectsFlags.Output, "text", "json")
 
-func buildClient() (*http.Client, error) {
-	jar, err := cookiejar.New(&cookiejar.Options{
-		PublicSuffixList: publicsuffix.List,
-	})
+	var accountFlags struct {
+		Output string
+	}
+	accountCmd := app.Command("account", "manage the account of the Prott.app")
+	accountInfoCmd := accountCmd.Command("info", "show the authenticated account")
+	accountInfoCmd.Flag("output", "an output format (text or json)").Default("text").EnumVar(&accountFlags.Output, "text", "json")
+
+	command, err := app.Parse(os.Args[1:])
 	if err != nil {
-		return nil, err
+		return usageError{err}
 	}
-	// TODO: setCookies from cookie-file
-	client := &http.Client{
-		Jar: jar,
+	level := logNormal
+	switch {
+	case flags.Verbose && flags.Quiet:
+		return usageError{errors.New("--verbose conflicts with --quiet")}
+	case flags.Verbose:
+		level = logVerbose
+	case flags.Quiet:
+		level = logQuiet
 	}
-	return client, nil
-}
 
-func loginPrott(client *http.Client, email, pass string) error {
-	token := map[string]interface{}{
-		"user": map[string]interface{}{
-			"email":    email,
-			"password": pass,
-		},
+	log := newLogger(os.Stdout, flags.OutputFormat, level)
+	ctx := context.Background()
+
+	if flags.passwordFlag {
+		log.warn("password_flag", logFields{}, "--prott-password leaks the password into the shell history: let protter ask it, or use --keychain or --project-api-key")
 	}
-	js, err := json.Marshal(token)
-	if err != nil {
-		return err
+	if flags.APIKey == "" && flags.Keychain && command != loginCmd.FullCommand() {
+		if flags.APIKey, _, err = (credentialStore{}).get(apiKeyAccount); err != nil {
+			return err
+		}
 	}
-	req, err := http.NewRequest("POST", "https://prottapp.com/users/sign_in.json", bytes.NewBuffer(js))
+
+	cookieFile, err := expandHome(flags.CookieFile)
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "sketch")
-	req.Header.Set("App-Type", "sketch")
-	res, err := client.Do(req)
+	client, restored, err := buildClient(flags.APIKey, cookieFile, flags.Connection, flags.Retry, log)
 	if err != nil {
 		return err
 	}
-	if res.StatusCode/100 != 2 {
-		return errors.New("invalid login")
+	saveSession := func() {
+		// an API key needs no session, and the jar of its run (not restored) must not replace a saved one
+		if cookieFile == "" || flags.APIKey != "" {
+			return
+		}
+		if err := client.HTTPClient.Jar.(*persistentJar).save(cookieFile); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to save the session to %s: %s\n", cookieFile, err)
+		}
+	}
+	defer saveSession()
+	signIn := func() error {
+		email, password, err := credentials(flags.ProttEmail, flags.ProttPassword, flags.Keychain)
+		if err != nil {
+			return err
+		}
+		flags.ProttEmail, flags.ProttPassword = email, password
+		if err := loginPrott(ctx, client, log, email, password); err != nil {
+			return err
+		}
+		// keep the new session even if the run fails later
+		saveSession()
+		return nil
+	}
+
+	// login (an API key needs no session, and a dry run from a --project-list-file sends nothing)
+	uploading := command == syncCmd.FullCommand() || command == uploadCmd.FullCommand()
+	offline := uploading && syncFlags.DryRun && flags.ProjectListFile != ""
+	if flags.APIKey == "" && (!restored || command == loginCmd.FullCommand()) && !offline {
+		if err := signIn(); err != nil {
+			return err
+		}
+	}
+
+	// get projects list
+	var projectList []Project
+	if offline {
+		if projectList, err = loadProjectList(flags.ProjectListFile); err != nil {
+			return err
+		}
+	} else {
+		projectList, err = getProjectList(ctx, client, log)
+		if err == prott.ErrSessionRejected && restored {
+			// the restored session is expired
+			if err := signIn(); err != nil {
+				return err
+			}
+			projectList, err = getProjectList(ctx, client, log)
+		}
+		if err != nil {
+			if flags.APIKey != "" && rejected(err) {
+				err = fmt.Errorf("invalid API key: %w", err)
+			}
+			return err
+		}
+		if flags.ProjectListFile != "" {
+			if err := saveProjectList(flags.ProjectListFile, projectList); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch command {
+	case loginCmd.FullCommand():
+		if flags.APIKey != "" {
+			log.event("login_checked", logFields{"projects": len(projectList)}, "the API key is valid")
+		} else {
+			log.event("session_saved", logFields{"email": flags.ProttEmail, "cookie_file": cookieFile}, "signed in as %s", flags.ProttEmail)
+		}
+		if flags.Keychain {
+			account, secret := flags.ProttEmail, flags.ProttPassword
+			if flags.APIKey != "" {
+				account, secret = apiKeyAccount, flags.APIKey
+			}
+			if err := (credentialStore{}).set(account, secret); err != nil {
+				return err
+			}
+			log.event("credential_saved", logFields{"account": account}, "saved the credential of %s to the credential store", account)
+		}
+	case projectsListCmd.FullCommand():
+		if err := showProjects(projectList, projectsFlags.Output); err != nil {
+			return err
+		}
+	case accountInfoCmd.FullCommand():
+		if err := showAccount(ctx, client, accountFlags.Output); err != nil {
+			return err
+		}
+	case screenInspectCmd.FullCommand():
+		if err := inspectScreen(ctx, client, projectList, inspectFlags.Project, inspectFlags.Screen, inspectFlags.Output); err != nil {
+			return err
+		}
+	case pullCmd.FullCommand():
+		if err := pullScreens(ctx, client, log, projectList, pullFlags.Project, pullFlags.Dir); err != nil {
+			return err
+		}
+	case syncCmd.FullCommand(), uploadCmd.FullCommand():
+		if command == uploadCmd.FullCommand() {
+			syncFlags.Projects = []string{uploadFlags.Project}
+		}
+		if artboard.Branch == "" {
+			// not in a git repository: leave screens untagged
+			artboard.Branch, _ = gitBranch(flags.CWDs[0])
+		}
+		if artboard.Commit == "" {
+			artboard.Commit, _ = gitOutput(flags.CWDs[0], "rev-parse", "--short", "HEAD")
+		}
+		if artboard.SessionID == "" {
+			if artboard.SessionID, err = newUUID(); err != nil {
+				return err
+			}
+		}
+		if newProject.Device == "" {
+			newProject.Device = artboard.PrototypeDevice
+		}
+		if newProject.Orientation == "" {
+			newProject.Orientation = artboard.Orientation
+		}
+		newProject.DescriptionDir = flags.CWDs[0]
+		if artboard.ArtifactURL == "" {
+			artboard.ArtifactURL = githubRunURL()
+		}
+		if err := artboard.loadScaleSuffixes(); err != nil {
+			return err
+		}
+		if err := artboard.loadSketchFile(); err != nil {
+			return err
+		}
+		syncFlags.Dirs = flags.CWDs
+		if exportFlags.Enabled {
+			if artboard.SketchFile == "" {
+				return usageError{errors.New("--sketch-export requires --sketch-file")}
+			}
+			if syncFlags.Watch {
+				return usageError{errors.New("--sketch-export conflicts with --watch")}
+			}
+			project := strings.TrimSuffix(filepath.Base(artboard.SketchFile), filepath.Ext(artboard.SketchFile))
+			if len(syncFlags.Projects) == 1 {
+				project = syncFlags.Projects[0]
+			}
+			dir, remove, err := exportSketchFile(log, exportFlags.Exporter, artboard.SketchFile, project)
+			if err != nil {
+				return err
+			}
+			defer remove()
+			syncFlags.Dirs = []string{dir}
+		}
+		configFile := flags.ConfigFile
+		if configFile == "" {
+			configFile = filepath.Join(flags.CWDs[0], defaultConfigFile)
+		}
+		conf, err := loadConfig(configFile, flags.ConfigFile != "")
+		if err != nil {
+			return err
+		}
+		syncFlags.Mappings = conf.Projects
+		if !filepath.IsAbs(syncFlags.StateFile) {
+			syncFlags.StateFile = filepath.Join(flags.CWDs[0], syncFlags.StateFile)
+		}
+		return syncArtboards(ctx, client, log, projectList, &syncFlags, &artboard, &newProject)
 	}
 	return nil
 }
 
-func getProjectList(client *http.Client) ([]Project, error) {
-	type account struct {
-		Name     string
-		Projects []Project
+// bearerTransport authenticates every request to the host of the Prott with an API key.
+// The key is not sent to the other hosts, e.g. of the screen images downloaded by pull.
+type bearerTransport struct {
+	key  string
+	host string
+	base http.RoundTripper
+}
+
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host {
+		return t.base.RoundTrip(req)
 	}
-	var accountMap map[string]account
-	req, err := http.NewRequest("GET", "https://prottapp.com/api/sketch_app/projects.json", nil)
+	// a RoundTripper must not modify the given request
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	r.Header.Set("Authorization", "Bearer "+t.key)
+	return t.base.RoundTrip(r)
+}
+
+// buildClient builds a client for the Prott API.
+// If cookieFile is given, the login session saved in it is restored and buildClient reports whether there was one.
+// Requests failed with a transient error are retried by the policy.
+func buildClient(apiKey, cookieFile string, conn connectionOptions, retry retryPolicy, log *logger) (*prott.Client, bool, error) {
+	base, err := conn.baseURL()
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "sketch")
-	req.Header.Set("App-Type", "sketch")
-	res, err := client.Do(req)
+	jar, err := cookiejar.New(&cookiejar.Options{
+		PublicSuffixList: publicsuffix.List,
+	})
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
-	if res.StatusCode != http.StatusOK {
-		return nil, errors.New("failed to get projects")
+	persistent := newPersistentJar(jar)
+	var restored bool
+	if cookieFile != "" && apiKey == "" {
+		if restored, err = persistent.load(cookieFile); err != nil {
+			return nil, false, err
+		}
 	}
-	if res.Body == nil {
-		return nil, errors.New("failed to get projects")
+	var transport http.RoundTripper
+	if transport, err = conn.transport(); err != nil {
+		return nil, false, err
 	}
-	defer res.Body.Close()
-	decoder := json.NewDecoder(res.Body)
-	if err := decoder.Decode(&accountMap); err != nil {
-		return nil, err
+	if apiKey != "" {
+		transport = &bearerTransport{key: apiKey, host: base.Host, base: transport}
+	}
+	httpClient := &http.Client{
+		Jar:       persistent,
+		Transport: &retryTransport{policy: retry, log: log, base: transport},
+	}
+	client := prott.NewClient(httpClient)
+	client.BaseURL = base.String()
+	return client, restored, nil
+}
+
+func loginPrott(ctx context.Context, client *prott.Client, log *logger, email, pass string) error {
+	if err := client.Login(ctx, email, pass); err != nil {
+		return err
 	}
-	var projects []Project
-	for _, p := range accountMap {
-		projects = append(projects, p.Projects...)
+	log.event("login", logFields{"email": email}, "")
+	return nil
+}
+
+func getProjectList(ctx context.Context, client *prott.Client, log *logger) ([]Project, error) {
+	projects, err := client.Projects(ctx)
+	if err != nil {
+		return nil, err
 	}
+	log.event("projects_listed", logFields{"count": len(projects)}, "")
 	return projects, nil
 }
 
 var (
 	screenReg      *regexp.Regexp
+	screenExtReg   = regexp.MustCompile(`(?i)\.(?:png|jpe?g|webp)$`)
 	errInvalidPath = errors.New("invalid path")
 )
 
@@ -174,7 +550,7 @@ func init() {
 			string([]rune{filepath.Separator}) +
 			`).exportedArtboards` +
 			string([]rune{filepath.Separator}) +
-			`(.*\.png)$`)
+			`(.*\.(?:png|jpe?g|webp))$`)
 }
 
 func parsePath(path string) (string, string, error) {
@@ -182,54 +558,52 @@ func parsePath(path string) (string, string, error) {
 	if len(mat) <= 1 {
 		return "", "", errInvalidPath
 	}
-	return filepath.Dir(mat[1]), strings.TrimSuffix(filepath.Base(mat[1]), `.png`), nil
+	return filepath.Dir(mat[1]), strings.TrimSuffix(filepath.Base(mat[1]), filepath.Ext(mat[1])), nil
 }
 
-func uploadScreen(client *http.Client, project Project, screen, path string) error {
-	var body bytes.Buffer
-	w := multipart.NewWriter(&body)
-	if fw, err := w.CreateFormField("project_id"); err != nil {
-		return err
-	} else if _, err = fw.Write([]byte(project.ID)); err != nil {
-		return err
-	}
-	if fw, err := w.CreateFormField("screen[sketch_artboard_id]"); err != nil {
-		return err
-	} else if _, err = fw.Write([]byte(screen)); err != nil {
-		// TODO: get sketch artboard id instead of its name
-		return err
+// uploadScreen uploads the artboard image at path as a screen of the project, along with the extra
+// form fields and files (field name -> file path). It creates a screen from the image, or replaces
+// the image of the existing screen if its ID is given. progress, if not nil, is called as the image is sent.
+func uploadScreen(ctx context.Context, client *prott.Client, log *logger, project Project, screen, path, existingID string, fields url.Values, files map[string]string, progress func(sent, total int64)) (Screen, error) {
+	u := screenUpload(project, screen, path, fields, files)
+	u.Progress = progress
+	method := "POST"
+	if existingID != "" {
+		method = "PATCH"
 	}
-	if fw, err := w.CreateFormField("screen[name]"); err != nil {
-		return err
-	} else if _, err = fw.Write([]byte(screen)); err != nil {
-		return err
-	}
-	// Add your image file
-	f, err := os.Open(path)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
+	return logUpload(log, method, project, screen, path, func() (Screen, *http.Response, error) {
+		if existingID != "" {
+			return client.ReplaceScreen(ctx, existingID, u)
+		}
+		return client.CreateScreen(ctx, u)
+	})
+}
 
-	if fw, err := w.CreateFormFile("screen[file]", path); err != nil {
-		return err
-	} else if _, err = io.Copy(fw, f); err != nil {
-		return err
+func screenUpload(project Project, screen, path string, fields url.Values, files map[string]string) prott.ScreenUpload {
+	u := prott.ScreenUpload{
+		ProjectID: project.ID,
+		Name:      screen,
+		Image:     path,
+		Fields:    url.Values{},
+		Files:     files,
 	}
-	w.Close()
-
-	req, err := http.NewRequest("POST", "https://prottapp.com/api/sketch_app/screens.json", &body)
-	if err != nil {
-		return err
+	for name, values := range fields {
+		if name == "screen[sketch_artboard_id]" {
+			u.ArtboardID = values[0]
+			continue
+		}
+		u.Fields[name] = values
 	}
-	req.Header.Set("Content-Type", w.FormDataContentType())
-	req.Header.Set("User-Agent", "sketch")
-	req.Header.Set("App-Type", "sketch")
-	if res, err := client.Do(req); err != nil {
-		return err
-	} else {
-		fmt.Println(res.Status)
+	return u
+}
+
+// logUpload logs the attempt and the result of an upload of a screen by fn.
+func logUpload(log *logger, method string, project Project, screen, path string, fn func() (Screen, *http.Response, error)) (Screen, error) {
+	log.event("upload_attempt", logFields{"project": project.Name, "screen": screen, "path": path, "method": method}, "")
+	uploaded, res, err := fn()
+	if res == nil {
+		return Screen{}, err
 	}
-	fmt.Println(project.Name, screen)
-	return nil
+	log.event("upload_result", logFields{"project": project.Name, "screen": screen, "path": path, "status": res.StatusCode, "error": errorField(err)}, "")
+	return uploaded, err
 }
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	for _, tt := range []struct {
+		path    string
+		project string
+		screen  string
+		err     error
+	}{
+		{"design/.exportedArtboards/App/Home.png", "App", "Home", nil},
+		{".exportedArtboards/App/Home.jpg", "App", "Home", nil},
+		{".exportedArtboards/App/Home.jpeg", "App", "Home", nil},
+		{".exportedArtboards/App/Home.webp", "App", "Home", nil},
+		{".exportedArtboards/App/Onboarding/Step 1@2x.png", "App/Onboarding", "Step 1@2x", nil},
+		{".exportedArtboards/App/Home.gif", "", "", errInvalidPath},
+		{".exportedArtboards/App/Home.png.txt", "", "", errInvalidPath},
+		{"design/App/Home.png", "", "", errInvalidPath},
+		{"design/not.exportedArtboards/App/Home.png", "", "", errInvalidPath},
+	} {
+		project, screen, err := parsePath(filepath.FromSlash(tt.path))
+		if err != tt.err || filepath.ToSlash(project) != tt.project || screen != tt.screen {
+			t.Errorf("parsePath(%q) = %q, %q, %v, want %q, %q, %v", tt.path, project, screen, err, tt.project, tt.screen, tt.err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// screenMetadata is the content of <screen>.protter_meta.json.
+type screenMetadata struct {
+	ProttID         string    `json:"prott_id"`
+	ProjectID       string    `json:"project_id"`
+	UploadTimestamp time.Time `json:"upload_timestamp"`
+	URL             string    `json:"url"`
+	Tags            []string  `json:"tags"`
+	Revision        int       `json:"revision"`
+}
+
+// writeMetadata writes the metadata of the uploaded screen beside the artboard image,
+// or under dir if it is given.
+func writeMetadata(dir string, project Project, screen Screen, path string) error {
+	file := metadataPath(dir, project, path)
+	if dir != "" {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return err
+		}
+	}
+	tags := screen.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	js, err := json.MarshalIndent(screenMetadata{
+		ProttID:         screen.ID,
+		ProjectID:       project.ID,
+		UploadTimestamp: time.Now().UTC(),
+		URL:             screen.URL,
+		Tags:            tags,
+		Revision:        screen.Revision,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(js, '\n'), 0644)
+}
+
+// metadataPath returns the metadata file of the artboard at path.
+// Under dir, it is in a directory per project and in the subdirectories of the artboard in the project directory,
+// not to write the metadata of the screens of the same name in different subdirectories to the same file.
+func metadataPath(dir string, project Project, path string) string {
+	file := sidecarPath(path, ".protter_meta.json")
+	if dir == "" {
+		return file
+	}
+	rel := filepath.Base(file)
+	if mat := screenReg.FindStringSubmatch(path); len(mat) > 1 {
+		if i := strings.IndexRune(mat[1], filepath.Separator); i >= 0 {
+			rel = sidecarPath(mat[1][i+1:], ".protter_meta.json")
+		}
+	}
+	return filepath.Join(dir, project.Name, rel)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteMetadataSameNameInSubdirectories(t *testing.T) {
+	root := filepath.Join(t.TempDir(), ".exportedArtboards", "App")
+	dir := t.TempDir()
+	project := Project{ID: "p", Name: "App"}
+	ids := map[string]string{"Onboarding": "1", "Signup": "2"} // subdirectory -> screen ID
+	for sub, id := range ids {
+		if err := writeMetadata(dir, project, Screen{ID: id, Name: "step1"}, filepath.Join(root, sub, "step1.png")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for sub, id := range ids {
+		js, err := ioutil.ReadFile(filepath.Join(dir, "App", sub, "step1.protter_meta.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var meta screenMetadata
+		if err := json.Unmarshal(js, &meta); err != nil {
+			t.Fatal(err)
+		}
+		if meta.ProttID != id {
+			t.Errorf("%s: got the metadata of a screen %q, want %q", sub, meta.ProttID, id)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// progressReport is the content of the --upload-progress-file.
+type progressReport struct {
+	SessionID  string           `json:"session_id,omitempty"` // the --upload-session-id
+	Total      int              `json:"total"`
+	Uploaded   int              `json:"uploaded"`
+	Failed     int              `json:"failed"`
+	Skipped    int              `json:"skipped"`
+	InProgress []progressScreen `json:"in_progress"`
+}
+
+type progressScreen struct {
+	Project string `json:"project"`
+	Screen  string `json:"screen"`
+
+	SentBytes  int64 `json:"sent_bytes,omitempty"`  // of the request being sent
+	TotalBytes int64 `json:"total_bytes,omitempty"` // of the request being sent
+}
+
+// progress counts the artboards of a sync.
+type progress struct {
+	mu         sync.Mutex
+	report     progressReport
+	inProgress map[progressScreen]int
+	sent       map[progressScreen][2]int64 // the bytes sent and total of the uploads in progress
+}
+
+func (p *progress) queued() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.report.Total++
+}
+
+func (p *progress) skipped() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.report.Total++
+	p.report.Skipped++
+}
+
+func (p *progress) started(project, screen string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.inProgress == nil {
+		p.inProgress = map[progressScreen]int{}
+	}
+	p.inProgress[progressScreen{Project: project, Screen: screen}]++
+}
+
+// sending records the bytes sent so far of the request uploading the screen.
+func (p *progress) sending(project, screen string, sent, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.sent == nil {
+		p.sent = map[progressScreen][2]int64{}
+	}
+	p.sent[progressScreen{Project: project, Screen: screen}] = [2]int64{sent, total}
+}
+
+// finished counts an upload done, and returns the uploads done and queued so far.
+func (p *progress) finished(project, screen string, err error) (int, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := progressScreen{Project: project, Screen: screen}
+	if p.inProgress[key]--; p.inProgress[key] <= 0 {
+		delete(p.inProgress, key)
+		delete(p.sent, key)
+	}
+	if err != nil {
+		p.report.Failed++
+	} else {
+		p.report.Uploaded++
+	}
+	return p.report.Uploaded + p.report.Failed, p.report.Total - p.report.Skipped
+}
+
+func (p *progress) snapshot() progressReport {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	report := p.report
+	report.InProgress = []progressScreen{}
+	for s := range p.inProgress {
+		sent := p.sent[s]
+		s.SentBytes, s.TotalBytes = sent[0], sent[1]
+		report.InProgress = append(report.InProgress, s)
+	}
+	sort.Slice(report.InProgress, func(i, j int) bool {
+		a, b := report.InProgress[i], report.InProgress[j]
+		if a.Project != b.Project {
+			return a.Project < b.Project
+		}
+		return a.Screen < b.Screen
+	})
+	return report
+}
+
+// writeEvery writes the progress to the file every interval until stop is closed,
+// and once more when stopped.
+func (p *progress) writeEvery(path string, interval time.Duration, stop <-chan struct{}) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.write(path); err != nil {
+				return err
+			}
+		case <-stop:
+			return p.write(path)
+		}
+	}
+}
+
+// write replaces the file with the progress atomically, to not let readers see a partial file.
+func (p *progress) write(path string) error {
+	js, err := json.MarshalIndent(p.snapshot(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(js, '\n'))
+}
+
+// writeFileAtomic replaces the file with data by renaming a temporary file,
+// so that readers never see a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/wacul/protter/prott"
+)
+
+type Team = prott.Team
+
+// projectOptions holds the attributes given to projects created (or updated) by protter.
+type projectOptions struct {
+	Privacy  string
+	Team     string
+	Language string
+	Tags     []string
+	TagFile  string
+	Slug     string
+
+	Device      string // a prototype device ID
+	Orientation string
+
+	Description     string
+	DescriptionLog  bool   // --project-description-from-git-log
+	DescriptionTmpl string // a --format of git log
+	DescriptionDir  string // the repository to read the git log of
+
+	Splash string // an image to upload as the splash screen of created projects
+
+	NotifyOnCreate []string // emails to invite to created projects
+	MemberRole     string
+
+	teams   []Team // cache of getTeamList
+	gitLog  string // cache of the git log of the DescriptionDir
+	readLog bool
+}
+
+// fields builds the form fields of the project creation for a project named name.
+func (o *projectOptions) fields(ctx context.Context, client *prott.Client, name string) (url.Values, error) {
+	fields := url.Values{}
+	fields.Set("project[name]", name)
+	if o.Privacy != "" {
+		fields.Set("project[privacy]", o.Privacy)
+	}
+	if o.Language != "" {
+		fields.Set("project[language]", o.Language)
+	}
+	if o.Slug != "" {
+		fields.Set("project[slug]", o.Slug)
+	}
+	description := o.Description
+	if o.DescriptionLog {
+		var err error
+		if description, err = o.description(); err != nil {
+			return nil, fmt.Errorf("failed to create a project %q: failed to read the git log: %s", name, err)
+		}
+	}
+	if description != "" {
+		fields.Set("project[description]", description)
+	}
+	if o.Device != "" {
+		fields.Set("project[device_id]", o.Device)
+	}
+	if o.Orientation != "" {
+		fields.Set("project[orientation]", o.Orientation)
+	}
+	if o.Team != "" {
+		team, err := o.findTeam(ctx, client, o.Team)
+		if err != nil {
+			return nil, err
+		}
+		fields.Set("project[team_id]", team.ID)
+	}
+	tags, err := o.tags(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tags {
+		fields.Add("project[tags][]", t)
+	}
+	return fields, nil
+}
+
+// description returns the git log for --project-description-from-git-log, read on the first project created.
+func (o *projectOptions) description() (string, error) {
+	if !o.readLog {
+		log, err := gitLog(o.DescriptionDir, o.DescriptionTmpl)
+		if err != nil {
+			return "", err
+		}
+		o.gitLog, o.readLog = log, true
+	}
+	return o.gitLog, nil
+}
+
+// create creates a project named name on Prott, and uploads the --artboard-prototype-splash to it.
+// If the --project-url-slug is taken, a numeric suffix is appended to it.
+func (o *projectOptions) create(ctx context.Context, client *prott.Client, log *logger, name string) (Project, error) {
+	fields, err := o.fields(ctx, client, name)
+	if err != nil {
+		return Project{}, err
+	}
+	for i := 2; ; i++ {
+		project, err := client.CreateProject(ctx, fields)
+		if err == nil {
+			if o.Splash != "" {
+				if err := client.UploadSplash(ctx, project, o.Splash); err != nil {
+					return project, err
+				}
+			}
+			return project, o.invite(ctx, client, log, project)
+		}
+		if err != prott.ErrSlugTaken || i > maxSlugSuffix {
+			return project, err
+		}
+		slug := fmt.Sprintf("%s-%d", o.Slug, i)
+		log.warn("slug_taken", logFields{"project": name, "slug": fields.Get("project[slug]"), "retry": slug}, "a slug %q is already taken: trying %q", fields.Get("project[slug]"), slug)
+		fields.Set("project[slug]", slug)
+	}
+}
+
+const maxSlugSuffix = 10
+
+// invite invites the --project-notify-on-create emails to the created project.
+func (o *projectOptions) invite(ctx context.Context, client *prott.Client, log *logger, project Project) error {
+	for _, email := range o.NotifyOnCreate {
+		if err := client.InviteMember(ctx, project, email, o.MemberRole); err != nil {
+			return err
+		}
+		log.event("member_invited", logFields{"project": project.Name, "email": email, "role": o.MemberRole}, "invited %s to a project %q as %s", email, project.Name, o.MemberRole)
+	}
+	return nil
+}
+
+var slugReg = regexp.MustCompile(`^[0-9A-Za-z-]+$`)
+
+func validateSlug(slug string) error {
+	if !slugReg.MatchString(slug) {
+		return fmt.Errorf("invalid slug %q: only alphanumeric characters and hyphens are allowed", slug)
+	}
+	return nil
+}
+
+// updateFields builds the form fields to update an existing project named name with.
+func (o *projectOptions) updateFields(name string) (url.Values, error) {
+	fields := url.Values{}
+	if o.Language != "" {
+		fields.Set("project[language]", o.Language)
+	}
+	tags, err := o.tags(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tags {
+		fields.Add("project[tags][]", t)
+	}
+	return fields, nil
+}
+
+func (o *projectOptions) tags(name string) ([]string, error) {
+	tags := append([]string{}, o.Tags...)
+	if o.TagFile != "" {
+		var fileTags []string
+		if _, err := lookupScreenMap(o.TagFile, name, &fileTags); err != nil {
+			return nil, err
+		}
+		tags = append(tags, fileTags...)
+	}
+	return tags, nil
+}
+
+// setCoverScreen makes the first screen of the project whose name matches the glob pattern its cover.
+// It reports whether a screen matched.
+func setCoverScreen(ctx context.Context, client *prott.Client, project Project, pattern string) (bool, error) {
+	screens, err := client.Screens(ctx, project.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, screen := range screens {
+		if ok, _ := path.Match(pattern, screen.Name); !ok {
+			continue
+		}
+		fields := url.Values{}
+		fields.Set("project[cover_screen_id]", screen.ID)
+		return true, client.UpdateProject(ctx, project, fields)
+	}
+	return false, nil
+}
+
+func (o *projectOptions) findTeam(ctx context.Context, client *prott.Client, name string) (Team, error) {
+	if o.teams == nil {
+		teams, err := client.Teams(ctx)
+		if err != nil {
+			return Team{}, err
+		}
+		o.teams = teams
+	}
+	for _, t := range o.teams {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	return Team{}, fmt.Errorf("a team %q is not exist", name)
+}
+
+// showProjects prints the projects sorted by their names.
+func showProjects(projects []Project, output string) error {
+	sorted := append([]Project{}, projects...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	if output == "json" {
+		return json.NewEncoder(os.Stdout).Encode(sorted)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tID")
+	for _, p := range sorted {
+		fmt.Fprintf(w, "%s\t%s\n", p.Name, p.ID)
+	}
+	return w.Flush()
+}
+
+// loadProjectList reads the projects saved by saveProjectList.
+func loadProjectList(path string) ([]Project, error) {
+	var projects []Project
+	if ok, err := loadJSON(path, &projects); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, fmt.Errorf("a project list %s is not exist", path)
+	}
+	return projects, nil
+}
+
+func saveProjectList(path string, projects []Project) error {
+	js, err := json.MarshalIndent(projects, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(js, '\n'), 0644)
+}
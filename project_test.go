@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUpdateFields(t *testing.T) {
+	o := projectOptions{Language: "ja", Tags: []string{"ios", "v2"}}
+	fields, err := o.updateFields("App")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fields.Get("project[language]"); got != "ja" {
+		t.Errorf("got a language %q, want ja", got)
+	}
+	if got := fields["project[tags][]"]; !reflect.DeepEqual(got, []string{"ios", "v2"}) {
+		t.Errorf("got the tags %v, want [ios v2]", got)
+	}
+
+	fields, err = (&projectOptions{}).updateFields("App")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 0 {
+		t.Errorf("got %v without the options, want no update", fields)
+	}
+}
+
+func TestFieldsDescriptionFromGitLog(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("no git")
+	}
+	o := projectOptions{DescriptionLog: true, DescriptionDir: t.TempDir()}
+	if _, err := o.fields(context.Background(), nil, "App"); err == nil || !strings.Contains(err.Error(), `"App"`) {
+		t.Errorf("out of a git repository: got %v, want an error creating a project \"App\"", err)
+	}
+
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.name=protter", "-c", "user.email=protter@example.com", "commit", "-q", "--allow-empty", "-m", "first"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s %s", args, err, out)
+		}
+	}
+	o = projectOptions{DescriptionLog: true, DescriptionDir: dir, DescriptionTmpl: "%s"}
+	fields, err := o.fields(context.Background(), nil, "App")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fields.Get("project[description]"); got != "first" {
+		t.Errorf("got a description %q, want first", got)
+	}
+}
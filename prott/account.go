@@ -0,0 +1,52 @@
+package prott
+
+import "context"
+
+// Account is the account of the login user (or of the API key).
+type Account struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Email        string    `json:"email"`
+	Plan         string    `json:"plan"`
+	Organization string    `json:"organization"`
+	RateLimit    RateLimit `json:"rate_limit"`
+}
+
+// RateLimit is the API rate limit of the account, read from the response headers.
+type RateLimit struct {
+	Limit     string `json:"limit,omitempty"`
+	Remaining string `json:"remaining,omitempty"`
+	Reset     string `json:"reset,omitempty"`
+}
+
+// Device is a device to preview the prototypes of projects on.
+type Device struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Width  int    `json:"width"`  // in points
+	Height int    `json:"height"` // in points
+}
+
+// Account returns the account of the login user.
+func (c *Client) Account(ctx context.Context) (Account, error) {
+	var account Account
+	res, err := c.getJSON(ctx, "/api/account.json", "get the account", &account)
+	if err != nil {
+		return Account{}, err
+	}
+	account.RateLimit = RateLimit{
+		Limit:     res.Header.Get("X-RateLimit-Limit"),
+		Remaining: res.Header.Get("X-RateLimit-Remaining"),
+		Reset:     res.Header.Get("X-RateLimit-Reset"),
+	}
+	return account, nil
+}
+
+// Devices returns the devices a prototype can be played on.
+func (c *Client) Devices(ctx context.Context) ([]Device, error) {
+	devices := []Device{}
+	if _, err := c.getJSON(ctx, "/api/sketch_app/devices.json", "get devices", &devices); err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
@@ -0,0 +1,129 @@
+// Package prott is a client of the Prott API used by the Sketch plugin.
+package prott
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultBaseURL is the base URL of the Prott API.
+const DefaultBaseURL = "https://prottapp.com"
+
+// Client sends requests to the Prott API.
+// A login session is kept in the cookie jar of the HTTPClient.
+type Client struct {
+	HTTPClient *http.Client
+	BaseURL    string // DefaultBaseURL if empty
+}
+
+// NewClient returns a client of the Prott API sending requests with httpClient
+// (http.DefaultClient if nil).
+func NewClient(httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{HTTPClient: httpClient, BaseURL: DefaultBaseURL}
+}
+
+// NewRequest builds a request to the path of the Prott API with the headers the Sketch plugin sends.
+func (c *Client) NewRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(base, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", "sketch")
+	req.Header.Set("App-Type", "sketch")
+	return req, nil
+}
+
+// Do sends the request, and returns a *StatusError for a response other than 2xx.
+// The body of a failed response is closed.
+func (c *Client) Do(req *http.Request, op string) (*http.Response, error) {
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode/100 != 2 {
+		if res.Body != nil {
+			res.Body.Close()
+		}
+		return res, newStatusError(op, res)
+	}
+	return res, nil
+}
+
+// send sends the request and decodes the JSON response into v (unless v is nil).
+func (c *Client) send(req *http.Request, op string, v interface{}) (*http.Response, error) {
+	res, err := c.Do(req, op)
+	if err != nil {
+		return res, err
+	}
+	if res.Body == nil {
+		return res, nil
+	}
+	defer res.Body.Close()
+	if v == nil {
+		return res, nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil && err != io.EOF {
+		return res, err
+	}
+	return res, nil
+}
+
+func (c *Client) getJSON(ctx context.Context, path, op string, v interface{}) (*http.Response, error) {
+	req, err := c.NewRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.send(req, op, v)
+}
+
+func (c *Client) sendJSON(ctx context.Context, method, path, op string, body, v interface{}) (*http.Response, error) {
+	js, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.NewRequest(ctx, method, path, bytes.NewReader(js))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.send(req, op, v)
+}
+
+func (c *Client) sendForm(ctx context.Context, method, path, op string, fields url.Values, v interface{}) (*http.Response, error) {
+	req, err := c.NewRequest(ctx, method, path, strings.NewReader(fields.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.send(req, op, v)
+}
+
+// Login signs in with the email and the password of the account.
+// It returns ErrInvalidLogin if they are rejected, and a *StatusError for any other failed response.
+func (c *Client) Login(ctx context.Context, email, password string) error {
+	body := map[string]interface{}{
+		"user": map[string]interface{}{
+			"email":    email,
+			"password": password,
+		},
+	}
+	_, err := c.sendJSON(ctx, "POST", "/users/sign_in.json", "sign in", body, nil)
+	if se, ok := err.(*StatusError); ok && (se.StatusCode == http.StatusUnauthorized || se.StatusCode == http.StatusUnprocessableEntity) {
+		return ErrInvalidLogin
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+package prott
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestClient returns a client of a Prott served by handler.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	c := NewClient(srv.Client())
+	c.BaseURL = srv.URL
+	return c
+}
+
+func TestLogin(t *testing.T) {
+	for _, tt := range []struct {
+		status int
+		want   error
+	}{
+		{http.StatusCreated, nil},
+		{http.StatusUnauthorized, ErrInvalidLogin},
+		{http.StatusUnprocessableEntity, ErrInvalidLogin},
+	} {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" || r.URL.Path != "/users/sign_in.json" {
+				t.Errorf("got a request %s %s, want POST /users/sign_in.json", r.Method, r.URL.Path)
+			}
+			if got := r.Header.Get("App-Type"); got != "sketch" {
+				t.Errorf("got an App-Type %q, want sketch", got)
+			}
+			w.WriteHeader(tt.status)
+		})
+		if err := c.Login(context.Background(), "a@example.com", "secret"); err != tt.want {
+			t.Errorf("status %d: got %v, want %v", tt.status, err, tt.want)
+		}
+	}
+}
+
+func TestLoginServerError(t *testing.T) {
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusInternalServerError} {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		})
+		err := c.Login(context.Background(), "a@example.com", "secret")
+		if se, ok := err.(*StatusError); !ok || se.StatusCode != status {
+			t.Errorf("status %d: got %v, want a *StatusError of the status", status, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package prott
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrInvalidLogin is returned by Login when the email or the password is wrong.
+	ErrInvalidLogin = errors.New("invalid login")
+	// ErrSessionRejected is returned when the login session is expired or missing.
+	ErrSessionRejected = errors.New("the session is rejected")
+	// ErrSlugTaken is returned by CreateProject when the given slug is used by another project.
+	ErrSlugTaken = errors.New("the slug of the project is already taken")
+	// ErrChunkedUploadUnsupported is returned by InitiateUpload when Prott does not accept chunked uploads.
+	ErrChunkedUploadUnsupported = errors.New("chunked upload is not supported")
+)
+
+// StatusAuthenticationTimeout is the non-standard status Rails answers an expired session (or CSRF token) with.
+const StatusAuthenticationTimeout = 419
+
+// StatusError is a request answered with a status other than 2xx.
+type StatusError struct {
+	Op         string // e.g. "get projects"
+	StatusCode int
+	Status     string
+	RetryAfter time.Duration // of the Retry-After header, or 0
+}
+
+func newStatusError(op string, res *http.Response) *StatusError {
+	return &StatusError{
+		Op:         op,
+		StatusCode: res.StatusCode,
+		Status:     res.Status,
+		RetryAfter: ParseRetryAfter(res.Header.Get("Retry-After")),
+	}
+}
+
+func (e *StatusError) Error() string {
+	return "failed to " + e.Op + ": " + e.Status
+}
+
+// IsNotFound reports whether err is a 404 response.
+func IsNotFound(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) && se.StatusCode == http.StatusNotFound
+}
+
+// ParseRetryAfter reads the delay in seconds or the date of a Retry-After header (0 if there is none).
+func ParseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if sec, err := strconv.Atoi(v); err == nil && sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
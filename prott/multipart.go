@@ -0,0 +1,179 @@
+package prott
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// formBody is a multipart/form-data body streamed from its files while it is sent,
+// so that an image of any size is never read into memory at once.
+type formBody struct {
+	fields   [][2]string // name, value in the order written
+	files    [][2]string // field name, file path
+	progress func(sent, total int64)
+}
+
+func (b *formBody) addField(name, value string) {
+	b.fields = append(b.fields, [2]string{name, value})
+}
+
+// addFields adds the fields sorted by their names, to write the same body every time.
+func (b *formBody) addFields(fields url.Values) {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range fields[name] {
+			b.addField(name, v)
+		}
+	}
+}
+
+// addFiles adds the files (field name -> file path) sorted by the field names.
+func (b *formBody) addFiles(files map[string]string) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.files = append(b.files, [2]string{name, files[name]})
+	}
+}
+
+// write writes the body with the boundary to w, copying each file by copyFile.
+func (b *formBody) write(w io.Writer, boundary string, copyFile func(w io.Writer, path string) error) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return err
+	}
+	for _, f := range b.fields {
+		if err := mw.WriteField(f[0], f[1]); err != nil {
+			return err
+		}
+	}
+	for _, f := range b.files {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, quoteEscaper.Replace(f[0]), quoteEscaper.Replace(f[1])))
+		h.Set("Content-Type", imageContentType(f[1]))
+		part, err := mw.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		if err := copyFile(part, f[1]); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+// size returns the length of the body, by the sizes of the files instead of reading them.
+func (b *formBody) size(boundary string) (int64, error) {
+	var files int64
+	counter := &countWriter{}
+	err := b.write(counter, boundary, func(_ io.Writer, path string) error {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		files += info.Size()
+		return nil
+	})
+	return counter.n + files, err
+}
+
+// open starts writing the body to a pipe, and returns its reading end.
+func (b *formBody) open(boundary string, size int64) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(b.write(pw, boundary, copyFile))
+	}()
+	if b.progress == nil {
+		return pr
+	}
+	return &progressReader{ReadCloser: pr, total: size, progress: b.progress}
+}
+
+// newFormRequest returns a request sending the form body.
+// The body is streamed with its length set, and GetBody writes it again from the files to retry the request.
+func (c *Client) newFormRequest(ctx context.Context, method, path string, b *formBody) (*http.Request, error) {
+	boundary := multipart.NewWriter(ioutil.Discard).Boundary()
+	size, err := b.size(boundary)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.NewRequest(ctx, method, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = b.open(boundary, size)
+	req.GetBody = func() (io.ReadCloser, error) {
+		return b.open(boundary, size), nil
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "multipart/form-data; boundary="+boundary)
+	return req, nil
+}
+
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+type countWriter struct {
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// progressReader reports the bytes read from the body so far.
+type progressReader struct {
+	io.ReadCloser
+	sent     int64
+	total    int64
+	progress func(sent, total int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.sent += int64(n)
+		r.progress(r.sent, r.total)
+	}
+	return n, err
+}
+
+// quoteEscaper escapes a form field name or a file name as mime/multipart does.
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// imageContentType returns the media type of an artboard image by its extension.
+func imageContentType(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".webp":
+		return "image/webp"
+	default:
+		return "image/png"
+	}
+}
@@ -0,0 +1,17 @@
+package prott
+
+import "testing"
+
+func TestImageContentType(t *testing.T) {
+	for path, want := range map[string]string{
+		"Home.png":  "image/png",
+		"Home.jpg":  "image/jpeg",
+		"Home.JPEG": "image/jpeg",
+		"Home.webp": "image/webp",
+		"Home":      "image/png",
+	} {
+		if got := imageContentType(path); got != want {
+			t.Errorf("imageContentType(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
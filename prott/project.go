@@ -0,0 +1,88 @@
+package prott
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Project is a prototype project on Prott.
+type Project struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Team is a team of accounts, which owns the projects created in it.
+type Team struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Projects returns the projects of all the accounts the login user belongs to.
+// It returns ErrSessionRejected if the user is not signed in.
+func (c *Client) Projects(ctx context.Context) ([]Project, error) {
+	type account struct {
+		Name     string
+		Projects []Project
+	}
+	var accountMap map[string]account
+	if _, err := c.getJSON(ctx, "/api/sketch_app/projects.json", "get projects", &accountMap); err != nil {
+		if se, ok := err.(*StatusError); ok && (se.StatusCode == http.StatusUnauthorized || se.StatusCode == StatusAuthenticationTimeout) {
+			return nil, ErrSessionRejected
+		}
+		return nil, err
+	}
+	var projects []Project
+	for _, p := range accountMap {
+		projects = append(projects, p.Projects...)
+	}
+	return projects, nil
+}
+
+// CreateProject creates a project with the form fields (project[name], project[slug]...).
+// It returns ErrSlugTaken if project[slug] is used by another project.
+func (c *Client) CreateProject(ctx context.Context, fields url.Values) (Project, error) {
+	var project Project
+	_, err := c.sendForm(ctx, "POST", "/api/sketch_app/projects.json", fmt.Sprintf("create a project %q", fields.Get("project[name]")), fields, &project)
+	if se, ok := err.(*StatusError); ok && se.StatusCode == http.StatusConflict && fields.Get("project[slug]") != "" {
+		return Project{}, ErrSlugTaken
+	}
+	return project, err
+}
+
+// UpdateProject updates the project with the form fields.
+func (c *Client) UpdateProject(ctx context.Context, project Project, fields url.Values) error {
+	_, err := c.sendForm(ctx, "PATCH", "/api/sketch_app/projects/"+project.ID+".json", fmt.Sprintf("update a project %q", project.Name), fields, nil)
+	return err
+}
+
+// UploadSplash uploads the image as the splash screen shown before the first screen in the prototype player.
+func (c *Client) UploadSplash(ctx context.Context, project Project, path string) error {
+	body := &formBody{}
+	body.addFiles(map[string]string{"project[splash_screen_file]": path})
+	req, err := c.newFormRequest(ctx, "PATCH", "/api/sketch_app/projects/"+project.ID+".json", body)
+	if err != nil {
+		return err
+	}
+	_, err = c.send(req, fmt.Sprintf("upload a splash screen of a project %q", project.Name), nil)
+	return err
+}
+
+// InviteMember invites the email to the project as the role.
+func (c *Client) InviteMember(ctx context.Context, project Project, email, role string) error {
+	fields := url.Values{}
+	fields.Set("membership[email]", email)
+	fields.Set("membership[role]", role)
+	_, err := c.sendForm(ctx, "POST", "/api/sketch_app/projects/"+project.ID+"/memberships.json", fmt.Sprintf("invite %s to a project %q", email, project.Name), fields, nil)
+	return err
+}
+
+// Teams returns the teams the login user belongs to.
+func (c *Client) Teams(ctx context.Context) ([]Team, error) {
+	teams := []Team{}
+	if _, err := c.getJSON(ctx, "/api/teams.json", "get teams", &teams); err != nil {
+		return nil, err
+	}
+	return teams, nil
+}
@@ -0,0 +1,76 @@
+package prott
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestProjects(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"1":{"name":"Personal","projects":[{"id":"p1","name":"App"}]},"2":{"name":"Team","projects":[{"id":"p2","name":"Web"}]}}`))
+	})
+	projects, err := c.Projects(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]string{}
+	for _, p := range projects {
+		names[p.ID] = p.Name
+	}
+	if len(projects) != 2 || names["p1"] != "App" || names["p2"] != "Web" {
+		t.Errorf("got %+v, want the projects of both accounts", projects)
+	}
+}
+
+func TestProjectsSessionRejected(t *testing.T) {
+	for _, tt := range []struct {
+		status int
+		want   error
+	}{
+		{http.StatusUnauthorized, ErrSessionRejected},
+		{StatusAuthenticationTimeout, ErrSessionRejected},
+	} {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		})
+		if _, err := c.Projects(context.Background()); err != tt.want {
+			t.Errorf("status %d: got %v, want %v", tt.status, err, tt.want)
+		}
+	}
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	var se *StatusError
+	if _, err := c.Projects(context.Background()); !errors.As(err, &se) || se.StatusCode != http.StatusInternalServerError {
+		t.Errorf("status 500: got %v, want a StatusError", err)
+	}
+}
+
+func TestCreateProjectSlugTaken(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("project[slug]") == "taken" {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		w.Write([]byte(`{"id":"p1","name":"` + r.FormValue("project[name]") + `"}`))
+	})
+	fields := url.Values{}
+	fields.Set("project[name]", "App")
+	fields.Set("project[slug]", "taken")
+	if _, err := c.CreateProject(context.Background(), fields); err != ErrSlugTaken {
+		t.Errorf("got %v, want ErrSlugTaken", err)
+	}
+
+	fields.Set("project[slug]", "app")
+	project, err := c.CreateProject(context.Background(), fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project != (Project{ID: "p1", Name: "App"}) {
+		t.Errorf("got %+v, want the created project", project)
+	}
+}
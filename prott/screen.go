@@ -0,0 +1,225 @@
+package prott
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Screen is a screen of a project, made from an artboard image.
+type Screen struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	URL      string   `json:"url"`
+	Tags     []string `json:"tags"`
+	Revision int      `json:"revision"`
+	ImageURL string   `json:"image_url"`
+	GroupID  string   `json:"group_id"` // empty if the screen is in no group
+}
+
+// Group is a group of screens in a project.
+type Group struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Screens returns the screens of the project.
+func (c *Client) Screens(ctx context.Context, projectID string) ([]Screen, error) {
+	screens := []Screen{}
+	if _, err := c.getJSON(ctx, "/api/sketch_app/projects/"+projectID+"/screens.json", "get screens", &screens); err != nil {
+		return nil, err
+	}
+	return screens, nil
+}
+
+// RawScreens returns the attributes of the screens of the project as they are, to read more than Screen has.
+func (c *Client) RawScreens(ctx context.Context, projectID string) ([]json.RawMessage, error) {
+	var screens []json.RawMessage
+	if _, err := c.getJSON(ctx, "/api/sketch_app/projects/"+projectID+"/screens.json", "get screens", &screens); err != nil {
+		return nil, err
+	}
+	return screens, nil
+}
+
+// Groups returns the screen groups of the project.
+// A project of an account without screen groups has none, not an error.
+func (c *Client) Groups(ctx context.Context, projectID string) ([]Group, error) {
+	groups := []Group{}
+	_, err := c.getJSON(ctx, "/api/sketch_app/projects/"+projectID+"/groups.json", "get groups", &groups)
+	if IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// CreateGroup creates a screen group of the name in the project.
+func (c *Client) CreateGroup(ctx context.Context, projectID, name string) (Group, error) {
+	fields := url.Values{}
+	fields.Set("group[name]", name)
+	var group Group
+	if _, err := c.sendForm(ctx, "POST", "/api/sketch_app/projects/"+projectID+"/groups.json", fmt.Sprintf("create a group %q", name), fields, &group); err != nil {
+		return Group{}, err
+	}
+	return group, nil
+}
+
+// Download writes the file at the URL (e.g. the ImageURL of a screen) to w.
+// A URL without a host is of the Prott.
+func (c *Client) Download(ctx context.Context, rawurl string, w io.Writer) error {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return err
+	}
+	var req *http.Request
+	if u.IsAbs() {
+		if req, err = http.NewRequest("GET", rawurl, nil); err != nil {
+			return err
+		}
+		req = req.WithContext(ctx)
+	} else if req, err = c.NewRequest(ctx, "GET", rawurl, nil); err != nil {
+		return err
+	}
+	res, err := c.Do(req, "download "+rawurl)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, err = io.Copy(w, res.Body)
+	return err
+}
+
+// UpdateScreen updates the screen with the form fields.
+func (c *Client) UpdateScreen(ctx context.Context, screen Screen, fields url.Values) error {
+	_, err := c.sendForm(ctx, "PATCH", "/api/sketch_app/screens/"+screen.ID+".json", fmt.Sprintf("update a screen %q", screen.Name), fields, nil)
+	return err
+}
+
+// DeleteScreen deletes the screen of the ID. A screen already deleted is not an error.
+func (c *Client) DeleteScreen(ctx context.Context, id string) error {
+	req, err := c.NewRequest(ctx, "DELETE", "/api/sketch_app/screens/"+id+".json", nil)
+	if err != nil {
+		return err
+	}
+	_, err = c.send(req, "delete a screen "+id, nil)
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// PostScreenResource posts v as JSON to a sub resource of the screen (e.g. "color_swatches").
+// A resource unavailable on the plan of the account is answered with 404 (see IsNotFound).
+func (c *Client) PostScreenResource(ctx context.Context, screen Screen, resource string, v interface{}) error {
+	_, err := c.sendJSON(ctx, "POST", "/api/sketch_app/screens/"+screen.ID+"/"+resource+".json", fmt.Sprintf("post %s of a screen %q", resource, screen.Name), v, nil)
+	return err
+}
+
+// ScreenUpload is the form to create (or replace) a screen from an artboard image.
+type ScreenUpload struct {
+	ProjectID  string
+	Name       string
+	ArtboardID string // the Sketch artboard, or Name if empty: Prott matches it by its name
+	Image      string // the path of the image, not sent by CompleteUpload
+	Fields     url.Values
+	Files      map[string]string // field name -> file path
+
+	// Progress, if set, is called as the body is sent with the bytes sent so far of total.
+	// It starts over from zero when the request is retried.
+	Progress func(sent, total int64)
+}
+
+// CreateScreen creates a screen of the project from the artboard image.
+// The response is returned (with its body closed) even for a failure, to tell its status.
+func (c *Client) CreateScreen(ctx context.Context, u ScreenUpload) (Screen, *http.Response, error) {
+	return c.postScreenForm(ctx, "POST", "/api/sketch_app/screens.json", u, true)
+}
+
+// ReplaceScreen replaces the image (and the attributes) of the existing screen of the ID.
+func (c *Client) ReplaceScreen(ctx context.Context, id string, u ScreenUpload) (Screen, *http.Response, error) {
+	return c.postScreenForm(ctx, "PATCH", "/api/sketch_app/screens/"+id+".json", u, true)
+}
+
+// CompleteUpload creates a screen from the image uploaded in chunks with InitiateUpload and UploadChunk.
+// Set screen[id] in the Fields to replace the image of an existing screen instead.
+func (c *Client) CompleteUpload(ctx context.Context, uploadID string, u ScreenUpload) (Screen, *http.Response, error) {
+	fields := url.Values{}
+	for name, values := range u.Fields {
+		fields[name] = values
+	}
+	fields.Set("screen[upload_id]", uploadID)
+	u.Fields = fields
+	return c.postScreenForm(ctx, "POST", "/api/sketch_app/screens/upload/complete.json", u, false)
+}
+
+func (c *Client) postScreenForm(ctx context.Context, method, path string, u ScreenUpload, withImage bool) (Screen, *http.Response, error) {
+	artboardID := u.ArtboardID
+	if artboardID == "" {
+		artboardID = u.Name
+	}
+	body := &formBody{progress: u.Progress}
+	body.addField("project_id", u.ProjectID)
+	body.addField("screen[sketch_artboard_id]", artboardID)
+	body.addField("screen[name]", u.Name)
+	fields := url.Values{}
+	for name, values := range u.Fields {
+		if name != "screen[sketch_artboard_id]" {
+			fields[name] = values
+		}
+	}
+	files := u.Files
+	if withImage {
+		files = map[string]string{"screen[file]": u.Image}
+		for name, file := range u.Files {
+			files[name] = file
+		}
+	}
+	body.addFields(fields)
+	body.addFiles(files)
+
+	req, err := c.newFormRequest(ctx, method, path, body)
+	if err != nil {
+		return Screen{}, nil, err
+	}
+	var screen Screen
+	res, err := c.send(req, fmt.Sprintf("upload a screen %q", u.Name), &screen)
+	if err != nil {
+		return Screen{}, res, err
+	}
+	return screen, res, nil
+}
+
+// InitiateUpload starts an upload in chunks of a file named name of size bytes, and returns its ID.
+// It returns ErrChunkedUploadUnsupported if Prott does not accept chunked uploads.
+func (c *Client) InitiateUpload(ctx context.Context, name string, size int64) (string, error) {
+	body := map[string]interface{}{
+		"file_name": name,
+		"size":      size,
+	}
+	var initiated struct {
+		UploadID string `json:"upload_id"`
+	}
+	_, err := c.sendJSON(ctx, "POST", "/api/sketch_app/screens/upload/initiate.json", "initiate an upload of "+name, body, &initiated)
+	if IsNotFound(err) {
+		return "", ErrChunkedUploadUnsupported
+	}
+	return initiated.UploadID, err
+}
+
+// UploadChunk uploads the chunk starting at the byte offset start of a total bytes file.
+func (c *Client) UploadChunk(ctx context.Context, uploadID string, chunk []byte, start, total int64) error {
+	req, err := c.NewRequest(ctx, "POST", "/api/sketch_app/screens/upload/"+uploadID+".json", bytes.NewReader(chunk))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, start+int64(len(chunk))-1, total))
+	_, err = c.send(req, fmt.Sprintf("upload a chunk of an upload %q", uploadID), nil)
+	return err
+}
@@ -0,0 +1,127 @@
+package prott
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestImage(t *testing.T, size int) string {
+	path := filepath.Join(t.TempDir(), "Home.png")
+	if err := ioutil.WriteFile(path, bytes.Repeat([]byte{0x89}, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCreateScreenStreamsBody(t *testing.T) {
+	image := writeTestImage(t, 1<<20)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.ContentLength != int64(len(body)) {
+			t.Errorf("got a Content-Length %d, want the %d bytes of the body", r.ContentLength, len(body))
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if err := r.ParseMultipartForm(1 << 10); err != nil {
+			t.Fatal(err)
+		}
+		for name, want := range map[string]string{
+			"project_id":                 "p1",
+			"screen[name]":               "Home",
+			"screen[sketch_artboard_id]": "Home", // the name without an ArtboardID
+			"screen[branch]":             "main",
+		} {
+			if got := r.FormValue(name); got != want {
+				t.Errorf("got %s=%q, want %q", name, got, want)
+			}
+		}
+		if files := r.MultipartForm.File["screen[file]"]; len(files) != 1 || files[0].Size != 1<<20 {
+			t.Errorf("got the files %v, want the image", files)
+		}
+		w.Write([]byte(`{"id":"s1","name":"Home"}`))
+	})
+
+	var sent, total int64
+	screen, _, err := c.CreateScreen(context.Background(), ScreenUpload{
+		ProjectID: "p1",
+		Name:      "Home",
+		Image:     image,
+		Fields:    map[string][]string{"screen[branch]": {"main"}},
+		Progress:  func(s, n int64) { sent, total = s, n },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if screen.ID != "s1" {
+		t.Errorf("got a screen %+v, want s1", screen)
+	}
+	if sent == 0 || sent != total {
+		t.Errorf("got the progress %d/%d, want the whole body sent", sent, total)
+	}
+}
+
+func TestFormRequestGetBody(t *testing.T) {
+	image := writeTestImage(t, 100<<10)
+	body := &formBody{}
+	body.addField("screen[name]", "Home")
+	body.addFiles(map[string]string{"screen[file]": image})
+	req, err := NewClient(nil).newFormRequest(context.Background(), "POST", "/", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(first)) != req.ContentLength {
+		t.Errorf("got %d bytes, want the Content-Length %d", len(first), req.ContentLength)
+	}
+	replay, err := req.GetBody()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := ioutil.ReadAll(replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Error("GetBody wrote a different body")
+	}
+}
+
+func TestFormRequestMissingFile(t *testing.T) {
+	body := &formBody{}
+	body.addFiles(map[string]string{"screen[file]": filepath.Join(t.TempDir(), "missing.png")})
+	if _, err := NewClient(nil).newFormRequest(context.Background(), "POST", "/", body); !os.IsNotExist(err) {
+		t.Errorf("got %v, want the file not found before sending", err)
+	}
+}
+
+func TestInitiateUploadUnsupported(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	if _, err := c.InitiateUpload(context.Background(), "Home.png", 100); err != ErrChunkedUploadUnsupported {
+		t.Errorf("got %v, want ErrChunkedUploadUnsupported", err)
+	}
+}
+
+func TestInitiateUpload(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"upload_id":"u1"}`))
+	})
+	id, err := c.InitiateUpload(context.Background(), "Home.png", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "u1" {
+		t.Errorf("got an upload ID %q, want u1", id)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/wacul/protter/prott"
+)
+
+// pullScreens downloads the images of the screens of the project into dir, named by the screen names.
+// A screen in a group is downloaded into the directory of the group, and "/" in a screen name makes directories
+// as in the exported artboards of Sketch.
+func pullScreens(ctx context.Context, client *prott.Client, log *logger, projectList []Project, projectName, dir string) error {
+	project, err := findProject(projectList, projectName)
+	if err != nil {
+		return err
+	}
+	screens, err := client.Screens(ctx, project.ID)
+	if err != nil {
+		return err
+	}
+	groups, err := client.Groups(ctx, project.ID)
+	if err != nil {
+		return err
+	}
+	groupNames := make(map[string]string, len(groups))
+	for _, g := range groups {
+		groupNames[g.ID] = g.Name
+	}
+
+	pulled := map[string]string{} // path -> the screen downloaded to it
+	for _, screen := range screens {
+		if screen.ImageURL == "" {
+			log.warn("pull_skipped", logFields{"project": project.Name, "screen": screen.Name, "reason": "no_image"},
+				"skipped a screen %q: no image", screen.Name)
+			continue
+		}
+		path, ok := pullPath(dir, groupNames[screen.GroupID], screen)
+		if !ok {
+			log.warn("pull_skipped", logFields{"project": project.Name, "screen": screen.Name, "reason": "invalid_name"},
+				"skipped a screen %q: its name is out of %s", screen.Name, dir)
+			continue
+		}
+		if other, ok := pulled[path]; ok {
+			log.warn("pull_skipped", logFields{"project": project.Name, "screen": screen.Name, "path": path, "reason": "duplicated"},
+				"skipped a screen %q: %s is downloaded from a screen %q of the same name", screen.Name, path, other)
+			continue
+		}
+		if err := download(ctx, client, screen.ImageURL, path); err != nil {
+			return fmt.Errorf("failed to download a screen %q: %s", screen.Name, err)
+		}
+		pulled[path] = screen.Name
+		log.event("pulled", logFields{"project": project.Name, "screen": screen.Name, "path": path},
+			"downloaded a screen %q to %s", screen.Name, path)
+	}
+	log.event("pull_finished", logFields{"project": project.Name, "screens": len(pulled), "dir": dir},
+		"downloaded %d screens of a project %q to %s", len(pulled), project.Name, dir)
+	return nil
+}
+
+// pullPath returns the path in dir to download the screen to, with the extension of its image URL.
+// It reports false for a name like "../x", not to write out of dir.
+func pullPath(dir, group string, screen Screen) (string, bool) {
+	ext := ".png"
+	if u, err := url.Parse(screen.ImageURL); err == nil && screenExtReg.MatchString(u.Path) {
+		ext = strings.ToLower(path.Ext(u.Path))
+	}
+	p := filepath.Join(dir, filepath.FromSlash(group), filepath.FromSlash(screen.Name)+ext)
+	if rel, err := filepath.Rel(dir, p); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return p, true
+}
+
+// download writes the file at the URL to path by renaming a temporary file,
+// not to leave a partial image if the download fails.
+func download(ctx context.Context, client *prott.Client, rawurl, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+	if err := client.Download(ctx, rawurl, tmp); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// uploadJob is an artboard waiting to be uploaded.
+type uploadJob struct {
+	project Project
+	screen  string
+	path    string
+
+	// small jobs are uploaded first with --upload-concurrency-by-size
+	prioritized bool
+	size        int64
+
+	seq int // order of the push
+
+	file fileState // the file recorded in the --state-file after the upload
+}
+
+// jobHeap orders prioritized jobs by size before the others in the order of the push.
+type jobHeap []uploadJob
+
+func (h jobHeap) Len() int { return len(h) }
+func (h jobHeap) Less(i, j int) bool {
+	a, b := h[i], h[j]
+	if a.prioritized != b.prioritized {
+		return a.prioritized
+	}
+	if a.prioritized && a.size != b.size {
+		return a.size < b.size
+	}
+	return a.seq < b.seq
+}
+func (h jobHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *jobHeap) Push(x interface{}) { *h = append(*h, x.(uploadJob)) }
+func (h *jobHeap) Pop() interface{} {
+	old := *h
+	job := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return job
+}
+
+// uploadQueue is a queue of uploadJobs shared by the walk and the upload workers.
+type uploadQueue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	jobs   jobHeap
+	seq    int
+	closed bool
+
+	drainOnce sync.Once
+	drainc    chan struct{}
+}
+
+func newUploadQueue() *uploadQueue {
+	q := &uploadQueue{drainc: make(chan struct{})}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *uploadQueue) push(job uploadJob) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job.seq = q.seq
+	q.seq++
+	heap.Push(&q.jobs, job)
+	q.cond.Signal()
+}
+
+// close tells the workers that no more jobs will be pushed.
+func (q *uploadQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// pop waits for a job. It returns false once the queue is closed and empty.
+func (q *uploadQueue) pop() (uploadJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.jobs) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if len(q.jobs) == 0 {
+		q.drainOnce.Do(func() { close(q.drainc) })
+		return uploadJob{}, false
+	}
+	return heap.Pop(&q.jobs).(uploadJob), true
+}
+
+// drained is closed once the queue is closed and all of its jobs are taken.
+func (q *uploadQueue) drained() <-chan struct{} {
+	return q.drainc
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+)
+
+// retryEntry is an upload recorded in the --upload-retry-file.
+type retryEntry struct {
+	Project string `json:"project"`
+	Screen  string `json:"screen"`
+	Path    string `json:"path"`
+}
+
+func loadRetryEntries(path string) ([]retryEntry, error) {
+	var entries []retryEntry
+	if _, err := loadJSON(path, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// updateRetryEntries rewrites the retry file with entries modified by update.
+func updateRetryEntries(path string, update func([]retryEntry) []retryEntry) error {
+	entries, err := loadRetryEntries(path)
+	if err != nil {
+		return err
+	}
+	entries = update(entries)
+	if entries == nil {
+		entries = []retryEntry{}
+	}
+	js, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(js, '\n'), 0644)
+}
+
+func removeRetryEntry(entries []retryEntry, e retryEntry) []retryEntry {
+	var rest []retryEntry
+	for _, r := range entries {
+		if r != e {
+			rest = append(rest, r)
+		}
+	}
+	return rest
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/wacul/protter/prott"
+)
+
+// inspectKeys are the screen attributes shown first (in this order) by inspectScreen.
+var inspectKeys = []string{
+	"id",
+	"name",
+	"width",
+	"height",
+	"created_at",
+	"updated_at",
+	"tags",
+	"description",
+	"revision",
+	"url",
+	"image_url",
+}
+
+// screenKey identifies a screen of a project: by its name, and with --subdirectories=group by its group too.
+type screenKey struct {
+	Group string // the group ID, "" if the groups are not told apart
+	Name  string
+}
+
+// getScreenList returns the IDs of the screens of the project by their names (and groups with byGroup).
+func getScreenList(ctx context.Context, client *prott.Client, projectID string, byGroup bool) (map[screenKey]string, error) {
+	screens, err := client.Screens(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[screenKey]string, len(screens))
+	for _, screen := range screens {
+		key := screenKey{Name: screen.Name}
+		if byGroup {
+			key.Group = screen.GroupID
+		}
+		if _, ok := ids[key]; !ok {
+			ids[key] = screen.ID
+		}
+	}
+	return ids, nil
+}
+
+func findProject(projectList []Project, name string) (Project, error) {
+	for _, p := range projectList {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return Project{}, fmt.Errorf("a project %q is not exist", name)
+}
+
+func inspectScreen(ctx context.Context, client *prott.Client, projectList []Project, projectName, screenName, output string) error {
+	project, err := findProject(projectList, projectName)
+	if err != nil {
+		return err
+	}
+	screens, err := client.RawScreens(ctx, project.ID)
+	if err != nil {
+		return err
+	}
+	for _, raw := range screens {
+		var attrs map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &attrs); err != nil {
+			return err
+		}
+		var name string
+		if err := json.Unmarshal(attrs["name"], &name); err != nil || name != screenName {
+			continue
+		}
+		if output == "json" {
+			_, err := os.Stdout.Write(append(raw, '\n'))
+			return err
+		}
+		return printAttributes(attrs)
+	}
+	return fmt.Errorf("a screen %q is not exist in the project %q", screenName, projectName)
+}
+
+func printAttributes(attrs map[string]json.RawMessage) error {
+	var keys []string
+	for _, k := range inspectKeys {
+		if _, ok := attrs[k]; ok {
+			keys = append(keys, k)
+		}
+	}
+	var custom []string
+	for k := range attrs {
+		if !contains(inspectKeys, k) {
+			custom = append(custom, k)
+		}
+	}
+	sort.Strings(custom)
+	keys = append(keys, custom...)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, k := range keys {
+		var s string
+		if err := json.Unmarshal(attrs[k], &s); err != nil {
+			// not a string: show it as compact JSON
+			s = string(attrs[k])
+		}
+		fmt.Fprintf(w, "%s:\t%s\n", k, s)
+	}
+	return w.Flush()
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// persistentJar is a cookie jar which can be saved to and restored from a file,
+// to keep a login session across runs.
+// The file is a JSON object mapping domains to the cookies they set.
+type persistentJar struct {
+	*cookiejar.Jar
+
+	mu      sync.Mutex
+	cookies map[string][]*http.Cookie // domain -> cookies
+}
+
+func newPersistentJar(jar *cookiejar.Jar) *persistentJar {
+	return &persistentJar{Jar: jar, cookies: map[string][]*http.Cookie{}}
+}
+
+func (j *persistentJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.Jar.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	domain := u.Hostname()
+	for _, c := range cookies {
+		kept := j.cookies[domain][:0]
+		for _, e := range j.cookies[domain] {
+			if e.Name != c.Name || e.Path != c.Path {
+				kept = append(kept, e)
+			}
+		}
+		j.cookies[domain] = append(kept, c)
+	}
+}
+
+// load restores the cookies saved in the file, and reports whether there was any.
+func (j *persistentJar) load(path string) (bool, error) {
+	saved := map[string][]*http.Cookie{}
+	if ok, err := loadJSON(path, &saved); err != nil || !ok {
+		return false, err
+	}
+	for domain, cookies := range saved {
+		j.SetCookies(&url.URL{Scheme: "https", Host: domain, Path: "/"}, cookies)
+	}
+	return len(saved) > 0, nil
+}
+
+// save writes the cookies which are not expired to the file.
+func (j *persistentJar) save(path string) error {
+	j.mu.Lock()
+	saved := map[string][]*http.Cookie{}
+	now := time.Now()
+	for domain, cookies := range j.cookies {
+		for _, c := range cookies {
+			if c.MaxAge < 0 || (!c.Expires.IsZero() && c.Expires.Before(now)) {
+				continue
+			}
+			saved[domain] = append(saved[domain], c)
+		}
+	}
+	j.mu.Unlock()
+
+	js, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(js, '\n'), 0600)
+}
+
+// expandHome expands a leading "~/" of the path to the home directory.
+func expandHome(path string) (string, error) {
+	if !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, path[2:]), nil
+}
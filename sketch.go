@@ -0,0 +1,133 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// sketchPage is the part of a page of a .sketch document read for the artboards.
+type sketchPage struct {
+	Layers []struct {
+		Class    string `json:"_class"`
+		ObjectID string `json:"do_objectID"`
+		Name     string `json:"name"`
+	} `json:"layers"`
+}
+
+// readSketchArtboards reads the IDs of the artboards (and symbols) of a .sketch document by their names.
+// An artboard named "Group/Home" is exported as Group/Home.png, so it is found by "Home" too.
+// Names shared by several artboards are left out not to replace the wrong screen.
+func readSketchArtboards(file string) (map[string]string, error) {
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open a sketch file %s: %s", file, err)
+	}
+	defer r.Close()
+	ids := map[string]string{}
+	ambiguous := map[string]bool{}
+	add := func(name, id string) {
+		if other, ok := ids[name]; ok && other != id {
+			ambiguous[name] = true
+		}
+		ids[name] = id
+	}
+	for _, f := range r.File {
+		if path.Dir(f.Name) != "pages" || path.Ext(f.Name) != ".json" {
+			continue
+		}
+		page, err := readSketchPage(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in a sketch file %s: %s", f.Name, file, err)
+		}
+		for _, l := range page.Layers {
+			if l.Class != "artboard" && l.Class != "symbolMaster" {
+				continue
+			}
+			add(l.Name, l.ObjectID)
+			if i := strings.LastIndex(l.Name, "/"); i >= 0 {
+				add(strings.TrimSpace(l.Name[i+1:]), l.ObjectID)
+			}
+		}
+	}
+	for name := range ambiguous {
+		delete(ids, name)
+	}
+	return ids, nil
+}
+
+func readSketchPage(f *zip.File) (sketchPage, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return sketchPage{}, err
+	}
+	defer rc.Close()
+	var page sketchPage
+	err = json.NewDecoder(rc).Decode(&page)
+	return page, err
+}
+
+// loadSketchFile reads the artboard IDs of the --sketch-file.
+func (o *artboardOptions) loadSketchFile() error {
+	if o.SketchFile == "" {
+		return nil
+	}
+	ids, err := readSketchArtboards(o.SketchFile)
+	if err != nil {
+		return err
+	}
+	o.sketchArtboards = ids
+	return nil
+}
+
+// sketchArtboardID returns the ID of the artboard in the --sketch-file the screen is exported from,
+// or "" if it is not found. The default @Nx suffix of the screen name is ignored.
+func (o *artboardOptions) sketchArtboardID(screen string) string {
+	if id, ok := o.sketchArtboards[screen]; ok {
+		return id
+	}
+	return o.sketchArtboards[scaleSuffixReg.ReplaceAllString(screen, "")]
+}
+
+// defaultSketchExporter is the --sketch-exporter: sketchtool bundled with Sketch.app
+// (Sketch.app/Contents/Resources/sketchtool/bin) on the PATH.
+const defaultSketchExporter = `sketchtool export artboards "$SKETCH_FILE" --output="$EXPORT_DIR"`
+
+// exportSketchFile exports the artboards of the sketch file with the exporter command into
+// <dir>/.exportedArtboards/<project>, and returns dir to walk. The command is run by sh with
+// SKETCH_FILE and EXPORT_DIR in the environment, like the hooks.
+// The directory is the same for the same file not to change the keys of the --state-file,
+// and is removed by the returned func.
+func exportSketchFile(log *logger, exporter, file, project string) (string, func(), error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", nil, err
+	}
+	sum := sha256.Sum256([]byte(abs))
+	dir := filepath.Join(os.TempDir(), "protter-export-"+hex.EncodeToString(sum[:6]))
+	remove := func() { os.RemoveAll(dir) }
+	// artboards deleted from the document must not be uploaded from the last export
+	remove()
+	exportDir := filepath.Join(dir, ".exportedArtboards", project)
+	if err := os.MkdirAll(exportDir, 0755); err != nil {
+		return "", nil, err
+	}
+	log.event("sketch_export", logFields{"file": file, "dir": exportDir}, "exporting the artboards of %s", file)
+	cmd := exec.Command("sh", "-c", exporter)
+	cmd.Env = append(os.Environ(), "SKETCH_FILE="+abs, "EXPORT_DIR="+exportDir)
+	// keep the standard output for the progress messages, as runHook does
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		remove()
+		return "", nil, fmt.Errorf("failed to export the artboards of %s by %q: %s", file, exporter, err)
+	}
+	return dir, remove, nil
+}
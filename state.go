@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// dedupStrategies are the ways --dedup-strategy tells an artboard is unchanged since its last upload.
+// "mtime" compares only the modification time and the size recorded in the state file:
+// it reads no file, but misses a file overwritten with the same modification time and size.
+// "sha256" compares the hash of the whole content: it catches any change, but reads every file.
+var dedupStrategies = []string{"none", "mtime", "sha256"}
+
+// fileState is an artboard recorded in the --state-file when it is uploaded.
+type fileState struct {
+	Project  string    `json:"project"`
+	Screen   string    `json:"screen"`
+	ScreenID string    `json:"screen_id,omitempty"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"mod_time"`
+	SHA256   string    `json:"sha256,omitempty"`
+}
+
+// syncState is the content of the --state-file.
+type syncState struct {
+	SessionID string               `json:"session_id,omitempty"` // the last --upload-session-id
+	Files     map[string]fileState `json:"files"`                // absolute path -> the last upload
+}
+
+// stateStore holds the --state-file while syncing.
+type stateStore struct {
+	path string
+
+	mu    sync.Mutex
+	state syncState
+}
+
+func loadState(path string) (*stateStore, error) {
+	s := &stateStore{path: path}
+	if _, err := loadJSON(path, &s.state); err != nil {
+		return nil, err
+	}
+	if s.state.Files == nil {
+		s.state.Files = map[string]fileState{}
+	}
+	return s, nil
+}
+
+// stateKey is the absolute path of the file, not to depend on the working directory of the run.
+func stateKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+func (s *stateStore) get(path string) (fileState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.state.Files[stateKey(path)]
+	return f, ok
+}
+
+func (s *stateStore) set(path string, f fileState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.Files[stateKey(path)] = f
+}
+
+func (s *stateStore) remove(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.state.Files, stateKey(path))
+}
+
+// vanished returns the recorded files which no longer exist, by their paths.
+func (s *stateStore) vanished() (map[string]fileState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files := map[string]fileState{}
+	for path, f := range s.state.Files {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			files[path] = f
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func (s *stateStore) save(sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.SessionID = sessionID
+	js, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, append(js, '\n'))
+}
+
+// statFile records the current modification time and size (and hash with sha256) of the file.
+func statFile(path, strategy string) (fileState, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}, err
+	}
+	f := fileState{Size: info.Size(), ModTime: info.ModTime()}
+	if strategy == "sha256" {
+		if f.SHA256, err = hashFile(path); err != nil {
+			return fileState{}, err
+		}
+	}
+	return f, nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// unchanged reports whether the file is the same as the last upload by the strategy.
+func (f fileState) unchanged(last fileState, strategy string) bool {
+	switch strategy {
+	case "mtime":
+		return f.Size == last.Size && f.ModTime.Equal(last.ModTime)
+	case "sha256":
+		return f.SHA256 != "" && f.SHA256 == last.SHA256
+	default:
+		return false
+	}
+}
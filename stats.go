@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// uploadStats counts the uploads since the last report of the --upload-stats-interval.
+type uploadStats struct {
+	uploads  atomic.Int64
+	bytes    atomic.Int64
+	errors   atomic.Int64
+	inFlight atomic.Int64 // uploads started and not added yet
+}
+
+func (s *uploadStats) started() {
+	s.inFlight.Add(1)
+}
+
+func (s *uploadStats) add(size int64, err error) {
+	s.inFlight.Add(-1)
+	if err != nil {
+		s.errors.Add(1)
+		return
+	}
+	s.uploads.Add(1)
+	s.bytes.Add(size)
+}
+
+// reportEvery logs the rolling statistics every interval until stop is closed.
+// An interval with no uploads in flight nor finished is not reported, e.g. while --watch waits for artboards.
+func (s *uploadStats) reportEvery(log *logger, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			uploads, bytes, errors := s.uploads.Swap(0), s.bytes.Swap(0), s.errors.Swap(0)
+			if uploads == 0 && errors == 0 && s.inFlight.Load() == 0 {
+				continue
+			}
+			mbps := float64(bytes) / (1 << 20) / interval.Seconds()
+			log.event("stats", logFields{"interval": interval.Seconds(), "uploads": uploads, "mb_per_sec": mbps, "errors": errors},
+				"last %s: %d uploads, %.2f MB/s, %d errors", interval, uploads, mbps, errors)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// projectSummary is the result of the uploads to a project shown by --upload-group-by-project.
+type projectSummary struct {
+	ProjectName string        `json:"project_name"`
+	Uploaded    int           `json:"uploaded"`
+	Skipped     int           `json:"skipped"`
+	Failed      int           `json:"failed"`
+	TotalBytes  int64         `json:"total_bytes"`
+	Duration    time.Duration `json:"-"`
+	Seconds     float64       `json:"duration"` // Duration in seconds
+}
+
+// fileResult is what a run did (or would do with --dry-run) with an artboard, listed in the JSON summary.
+type fileResult struct {
+	Project string `json:"project"`
+	Screen  string `json:"screen"`
+	File    string `json:"file"`
+	Action  string `json:"action"` // upload, skip or delete
+	Result  string `json:"result"` // planned, uploaded, deleted, failed, or the reason of a skip
+	Error   string `json:"error,omitempty"`
+}
+
+func (s *syncer) record(r fileResult) {
+	s.resultsMu.Lock()
+	defer s.resultsMu.Unlock()
+	s.results = append(s.results, r)
+}
+
+func (s *syncer) recordUpload(job uploadJob, err error) {
+	r := fileResult{Project: job.project.Name, Screen: job.screen, File: job.path, Action: "upload", Result: "uploaded"}
+	if err != nil {
+		r.Result, r.Error = "failed", err.Error()
+	}
+	s.record(r)
+}
+
+// sortedResults returns the results of the artboards sorted by their projects and screens.
+func (s *syncer) sortedResults() []fileResult {
+	s.resultsMu.Lock()
+	defer s.resultsMu.Unlock()
+	results := append([]fileResult{}, s.results...)
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Project != results[j].Project {
+			return results[i].Project < results[j].Project
+		}
+		return results[i].Screen < results[j].Screen
+	})
+	return results
+}
+
+// summary returns the results of the projects sorted by their names.
+func (s *syncer) summary() []projectSummary {
+	s.runsMu.Lock()
+	defer s.runsMu.Unlock()
+	summaries := make([]projectSummary, 0, len(s.runs))
+	for _, r := range s.runs {
+		r.mu.Lock()
+		p := projectSummary{
+			ProjectName: r.project.Name,
+			Uploaded:    r.screens - r.errors,
+			Skipped:     r.skipped,
+			Failed:      r.errors,
+			TotalBytes:  r.bytes,
+		}
+		if !r.startedAt.IsZero() {
+			p.Duration = r.finishedAt.Sub(r.startedAt)
+			p.Seconds = p.Duration.Seconds()
+		}
+		r.mu.Unlock()
+		summaries = append(summaries, p)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].ProjectName < summaries[j].ProjectName })
+	return summaries
+}
+
+// printSummary prints the total of the uploads, and the results per project with --upload-group-by-project.
+func (s *syncer) printSummary() error {
+	summaries := s.summary()
+	var total projectSummary
+	for _, p := range summaries {
+		total.Uploaded += p.Uploaded
+		total.Skipped += p.Skipped
+		total.Failed += p.Failed
+	}
+	fields := logFields{"session_id": s.artboard.SessionID, "uploaded": total.Uploaded, "skipped": total.Skipped, "failed": total.Failed, "results": s.sortedResults()}
+	if s.opts.GroupByProject {
+		fields["projects"] = summaries
+	}
+	s.log.event("summary", fields, "uploaded %d screens, skipped %d, failed %d", total.Uploaded, total.Skipped, total.Failed)
+	if !s.opts.GroupByProject || s.log.json {
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PROJECT\tUPLOADED\tSKIPPED\tFAILED\tBYTES\tDURATION")
+	for _, p := range summaries {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%s\n", p.ProjectName, p.Uploaded, p.Skipped, p.Failed, p.TotalBytes, p.Duration.Round(time.Millisecond))
+	}
+	return w.Flush()
+}
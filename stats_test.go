@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReportEveryOnlyWithUploads(t *testing.T) {
+	report := func(s *uploadStats) string {
+		var buf bytes.Buffer
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			s.reportEvery(newLogger(&buf, "text", logNormal), 10*time.Millisecond, stop)
+		}()
+		time.Sleep(50 * time.Millisecond)
+		close(stop)
+		<-done
+		return buf.String()
+	}
+
+	if out := report(&uploadStats{}); out != "" {
+		t.Errorf("reported %q with no uploads, want nothing", out)
+	}
+	var s uploadStats
+	s.started()
+	if out := report(&s); !strings.Contains(out, "0 uploads") {
+		t.Errorf("reported %q with an upload in flight, want its progress", out)
+	}
+}
@@ -0,0 +1,866 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/kyoh86/fastwalk"
+	"github.com/wacul/protter/prott"
+)
+
+// syncOptions holds the options of the sync command.
+type syncOptions struct {
+	Dirs                 []string
+	ParallelWalk         bool
+	SkipExistingProjects bool
+	MinExistingScreens   int
+	RetryFile            string
+	RetryFrom            string
+	Concurrency          int
+	RampStart            int
+	RampInterval         time.Duration
+	BySize               bool
+	BySizeThreshold      units.Base2Bytes
+	ProjectStartHook     string
+	ProjectFinishHook    string
+	CoverScreenPattern   string
+	ProgressFile         string
+	StatsInterval        time.Duration
+	ChunkSize            units.Base2Bytes
+	UploadAfter          timeValue
+	UploadBefore         timeValue
+	ExportMetadata       bool
+	MetadataDir          string
+	CreateMissing        bool
+	StateFile            string
+	DedupStrategy        string
+	SkipUnchangedMtime   bool
+	Incremental          bool
+	Prune                bool
+	GroupByProject       bool
+	DryRun               bool
+	DryRunConfirm        bool
+	Yes                  bool
+	Projects             []string
+	ForceRecreate        bool
+	Watch                bool
+	WatchDebounce        time.Duration
+	Files                []string // artboard images to upload to the --project instead of walking the Dirs
+	Mappings             []projectMapping
+	Subdirectories       string // one of subdirectoryModes
+}
+
+// syncer uploads the exported artboards to the projects on Prott.
+// The walk pushes artboards to the queue and the workers upload them.
+type syncer struct {
+	ctx        context.Context
+	client     *prott.Client
+	log        *logger
+	opts       *syncOptions
+	artboard   *artboardOptions
+	newProject *projectOptions
+	queue      *uploadQueue
+
+	mappings []projectMapping // of the --config, named after the projects
+
+	projectsMu sync.Mutex
+	projects   map[string]Project
+	missing    map[string]bool // names of the projects not exist on Prott
+	found      map[string]bool // names of the --project found in the walk
+
+	existingMu sync.Mutex
+	existing   map[string]bool // project name -> whether it has enough screens to be skipped
+
+	screenListsMu sync.Mutex
+	screenLists   map[string]map[screenKey]string // project name -> screen -> screen ID on Prott
+
+	namesMu sync.Mutex
+	names   map[string]map[string]string // project name -> screen name -> the subdirectory of its artboard with --subdirectories=tag
+
+	groupsMu sync.Mutex
+	groups   map[string]map[string]string // project name -> group name -> group ID on Prott
+
+	retryMu sync.Mutex
+
+	runsMu sync.Mutex
+	runs   map[string]*projectRun // project name -> uploads to the project
+
+	linksMu   sync.Mutex
+	links     []screenLink
+	screenIDs map[string]map[string]string // project name -> screen name -> uploaded screen ID
+
+	state *stateStore // nil with --dedup-strategy=none
+
+	progress progress
+	stats    uploadStats
+
+	errMu sync.Mutex
+	errs  uploadErrors // the errors of the workers
+
+	resultsMu sync.Mutex
+	results   []fileResult // for the summary
+
+	planMu   sync.Mutex
+	planned  map[string]int // project name -> screens which would be uploaded with --dry-run
+	unknowns int            // files skipped for their unknown project
+
+	plannedBytes int64 // of the files which would be uploaded with --dry-run
+}
+
+// uploadErrors are the errors of the uploads which failed in a sync.
+type uploadErrors []error
+
+func (e uploadErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// missingProjectError is a project whose artboards are skipped as it does not exist on Prott.
+type missingProjectError struct {
+	name string
+}
+
+func (e missingProjectError) Error() string {
+	return fmt.Sprintf("skipped a project %q: not exist (create it with --create-missing-projects)", e.name)
+}
+
+func syncArtboards(ctx context.Context, client *prott.Client, log *logger, projectList []Project, opts *syncOptions, artboard *artboardOptions, newProject *projectOptions) error {
+	if opts.Concurrency < 1 {
+		return errors.New("--parallelism must be at least 1")
+	}
+	if opts.SkipExistingProjects && opts.MinExistingScreens < 1 {
+		return errors.New("--min-existing-screens must be at least 1")
+	}
+	if !opts.UploadAfter.IsZero() && !opts.UploadBefore.IsZero() && !opts.UploadBefore.After(opts.UploadAfter.Time) {
+		return errors.New("--upload-before must be later than --upload-after")
+	}
+	if len(opts.Files) > 0 && opts.RetryFrom != "" {
+		return errors.New("--retry-from conflicts with the upload command")
+	}
+	if opts.Watch && (opts.DryRun || opts.RetryFrom != "" || len(opts.Files) > 0) {
+		return errors.New("--watch conflicts with --dry-run, --retry-from and the upload command")
+	}
+	if newProject.Splash != "" && opts.CreateMissing && !opts.DryRun {
+		if err := artboard.validateSplash(ctx, client, newProject.Splash, newProject.Device); err != nil {
+			return err
+		}
+	}
+	if newProject.Device != "" && opts.CreateMissing && !opts.DryRun {
+		if err := artboard.validateDevice(ctx, client, newProject.Device); err != nil {
+			return err
+		}
+	}
+	if opts.DryRunConfirm && !opts.DryRun {
+		// show the plan with a dry run without side effects before the sync
+		plan := *opts
+		plan.DryRun, plan.DryRunConfirm = true, false
+		plan.ProgressFile, plan.StatsInterval = "", 0
+		// the plan walks the artboards once, and the sync after the confirmation watches them
+		plan.Watch = false
+		plan.RetryFile, plan.ProjectStartHook, plan.ProjectFinishHook = "", "", ""
+		if err := syncArtboards(ctx, client, log, projectList, &plan, artboard, newProject); err != nil {
+			return err
+		}
+		if err := confirm("Proceed with upload? [y/N] ", opts.Yes); err != nil {
+			return err
+		}
+	}
+	s := &syncer{
+		ctx:        ctx,
+		client:     client,
+		log:        log,
+		opts:       opts,
+		artboard:   artboard,
+		newProject: newProject,
+		projects:   map[string]Project{},
+		missing:    map[string]bool{},
+		found:      map[string]bool{},
+		planned:    map[string]int{},
+		queue:      newUploadQueue(),
+		existing:   map[string]bool{},
+		runs:       map[string]*projectRun{},
+
+		screenIDs:   map[string]map[string]string{},
+		screenLists: map[string]map[screenKey]string{},
+		groups:      map[string]map[string]string{},
+		names:       map[string]map[string]string{},
+	}
+	s.progress.report.SessionID = artboard.SessionID
+	if opts.SkipUnchangedMtime {
+		if opts.DedupStrategy != "none" && opts.DedupStrategy != "mtime" {
+			return errors.New("--skip-unchanged-mtime conflicts with --dedup-strategy=" + opts.DedupStrategy)
+		}
+		opts.DedupStrategy = "mtime"
+	}
+	if (opts.Incremental || opts.Prune) && opts.DedupStrategy == "none" {
+		opts.DedupStrategy = "sha256"
+	}
+	if opts.DedupStrategy != "none" {
+		state, err := loadState(opts.StateFile)
+		if err != nil {
+			return err
+		}
+		s.state = state
+	}
+	mappings, err := resolveMappings(opts.Mappings, projectList)
+	if err != nil {
+		return err
+	}
+	s.mappings = mappings
+	for _, p := range projectList {
+		if len(opts.Projects) > 0 && !contains(opts.Projects, p.Name) {
+			continue
+		}
+		s.projects[p.Name] = p
+		log.event("project", logFields{"project": p.Name, "id": p.ID}, "%s", p.Name)
+	}
+
+	var progressErr error
+	stopProgress := make(chan struct{})
+	progressDone := make(chan struct{})
+	if opts.ProgressFile != "" {
+		go func() {
+			defer close(progressDone)
+			progressErr = s.progress.writeEvery(opts.ProgressFile, time.Second, stopProgress)
+		}()
+	} else {
+		close(progressDone)
+	}
+
+	stopStats := make(chan struct{})
+	defer close(stopStats)
+	if opts.StatsInterval > 0 && !opts.DryRun {
+		go s.stats.reportEvery(log, opts.StatsInterval, stopStats)
+	}
+
+	var workers sync.WaitGroup
+	workers.Add(1)
+	go s.runWorkers(&workers)
+
+	var w *watcher
+	if opts.Watch {
+		// take the files before the first walk not to miss those written during it
+		w = newWatcher(opts.Dirs, opts.WatchDebounce)
+		err = w.poll(nil)
+	}
+	if err == nil {
+		if opts.RetryFrom != "" {
+			err = s.enqueueRetries()
+		} else if len(opts.Files) > 0 {
+			err = s.enqueueFiles()
+		} else {
+			err = s.walkAll()
+		}
+	}
+	if err == nil && w != nil {
+		err = s.watch(w)
+	}
+	s.queue.close()
+	workers.Wait()
+	close(stopProgress)
+	<-progressDone
+	if err == nil && opts.Prune {
+		err = s.prune()
+	}
+	if opts.DryRun {
+		if err != nil {
+			return err
+		}
+		s.printPlan()
+		if errs := s.unfoundProjects(); len(errs) > 0 {
+			return uploadErrors(errs)
+		}
+		return nil
+	}
+	if s.state != nil {
+		// keep the uploaded files even if some of them failed
+		if err := s.state.save(artboard.SessionID); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if progressErr != nil {
+		return progressErr
+	}
+	if err := s.resolveLinks(); err != nil {
+		return err
+	}
+	if err := s.finishProjects(); err != nil {
+		return err
+	}
+	if err := s.printSummary(); err != nil {
+		return err
+	}
+	s.errs = append(s.errs, s.unfoundProjects()...)
+	var missing []string
+	for name := range s.missing {
+		missing = append(missing, name)
+	}
+	sort.Strings(missing)
+	for _, name := range missing {
+		s.errs = append(s.errs, missingProjectError{name})
+	}
+	if len(s.errs) > 0 {
+		return s.errs
+	}
+	return nil
+}
+
+func (s *syncer) walkAll() error {
+	if !s.opts.ParallelWalk {
+		for _, dir := range s.opts.Dirs {
+			if err := fastwalk.FastWalk(dir, s.walk); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(s.opts.Dirs))
+	for i, dir := range s.opts.Dirs {
+		wg.Add(1)
+		go func(i int, dir string) {
+			defer wg.Done()
+			errs[i] = fastwalk.FastWalk(dir, s.walk)
+		}(i, dir)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *syncer) walk(path string, typ os.FileMode) error {
+	projectName, screenName, err := parsePath(path)
+	switch err {
+	case nil:
+		// noop
+	case errInvalidPath:
+		return nil
+	default:
+		return err
+	}
+	projectName, _ = s.splitSubdirectory(projectName)
+	projectName, prefix := s.mapProject(projectName)
+	if !s.selected(projectName) {
+		return nil
+	}
+	return s.add(projectName, prefix+screenName, path)
+}
+
+// enqueueFiles queues the artboard images given to the upload command, named after their files.
+func (s *syncer) enqueueFiles() error {
+	projectName := s.opts.Projects[0]
+	s.selected(projectName)
+	for _, path := range s.opts.Files {
+		if !screenExtReg.MatchString(path) {
+			return fmt.Errorf("%s is not an artboard image (png, jpg or webp)", path)
+		}
+		screenName := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if err := s.add(projectName, screenName, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add queues the artboard image at path to upload as a screen of the project unless it is skipped.
+func (s *syncer) add(projectName, screenName, path string) error {
+	if s.artboard.isSidecar(path) {
+		return nil
+	}
+	if !s.opts.UploadAfter.IsZero() || !s.opts.UploadBefore.IsZero() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if (!s.opts.UploadAfter.IsZero() && !info.ModTime().After(s.opts.UploadAfter.Time)) ||
+			(!s.opts.UploadBefore.IsZero() && !info.ModTime().Before(s.opts.UploadBefore.Time)) {
+			// not resolved by s.project, not to create a project only for the artboards out of the window
+			s.skip(s.knownProject(projectName), screenName, path, "out_of_time_window",
+				"skipped %s: modified at %s, out of --upload-after/--upload-before", path, info.ModTime().Format(time.RFC3339))
+			return nil
+		}
+	}
+	screenName, _ = s.artboard.detectScale(screenName)
+	project, ok, err := s.project(projectName)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.skip(Project{Name: projectName}, screenName, path, "unknown_project", "a project %q is not exist", projectName)
+		s.planMu.Lock()
+		s.unknowns++
+		s.planMu.Unlock()
+		return nil // skip
+	}
+	if sub, other, ok := s.claimName(project, screenName, path); !ok {
+		s.log.warn("skipped", logFields{"project": project.Name, "screen": screenName, "path": path, "reason": "duplicate_name"},
+			"skipped %s: a screen %q of a project %q is uploaded from %q, tagged differently from %q", path, screenName, project.Name, other, sub)
+		s.countSkip(project, screenName, path, "duplicate_name")
+		return nil
+	}
+	if s.opts.SkipExistingProjects {
+		if skip, err := s.hasExistingScreens(project); err != nil {
+			return err
+		} else if skip {
+			s.skip(project, screenName, path, "existing_project", "")
+			return nil
+		}
+	}
+
+	job := uploadJob{project: project, screen: screenName, path: path}
+	if s.state != nil {
+		if job.file, err = statFile(path, s.opts.DedupStrategy); err != nil {
+			return err
+		}
+		if last, ok := s.state.get(path); ok && job.file.unchanged(last, s.opts.DedupStrategy) {
+			s.skip(project, screenName, path, "unchanged", "a screen %q is unchanged", screenName)
+			return nil
+		}
+	}
+	return s.enqueue(job)
+}
+
+// watch uploads the artboards written after the first walk until protter is interrupted.
+func (s *syncer) watch(w *watcher) error {
+	s.log.event("watch_started", logFields{"dirs": s.opts.Dirs, "debounce": s.opts.WatchDebounce.Seconds()}, "watching %s for exported artboards (press Ctrl-C to stop)", strings.Join(s.opts.Dirs, ", "))
+	err := w.run(s.ctx, func(path string) {
+		s.log.event("artboard_changed", logFields{"path": path}, "")
+		if err := s.walk(path, 0); err != nil {
+			s.setError(err)
+		}
+	})
+	s.log.event("watch_stopped", logFields{"error": errorField(err)}, "")
+	return err
+}
+
+// skip counts an artboard not uploaded for the reason, logging the message formatted with args.
+func (s *syncer) skip(project Project, screen, path, reason, format string, args ...interface{}) {
+	s.log.event("skipped", logFields{"project": project.Name, "screen": screen, "path": path, "reason": reason}, format, args...)
+	s.countSkip(project, screen, path, reason)
+}
+
+// countSkip counts the skipped artboard in the progress and the summary, for skip or a skip logged as a warning.
+func (s *syncer) countSkip(project Project, screen, path, reason string) {
+	s.progress.skipped()
+	s.projectRun(project).skip()
+	s.record(fileResult{Project: project.Name, Screen: screen, File: path, Action: "skip", Result: reason})
+}
+
+// selected reports whether the project is one of the --project, and records that it is found.
+func (s *syncer) selected(name string) bool {
+	if len(s.opts.Projects) == 0 {
+		return true
+	}
+	if !contains(s.opts.Projects, name) {
+		return false
+	}
+	s.projectsMu.Lock()
+	defer s.projectsMu.Unlock()
+	s.found[name] = true
+	return true
+}
+
+// prune deletes the screens on Prott whose files recorded in the --state-file no longer exist.
+func (s *syncer) prune() error {
+	files, err := s.state.vanished()
+	if err != nil {
+		return err
+	}
+	var paths []string
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		f := files[path]
+		if len(s.opts.Projects) > 0 && !contains(s.opts.Projects, f.Project) {
+			continue
+		}
+		if s.opts.DryRun {
+			s.log.event("would_prune", logFields{"project": f.Project, "screen": f.Screen, "path": path}, "would delete a screen %q of a project %q", f.Screen, f.Project)
+			s.record(fileResult{Project: f.Project, Screen: f.Screen, File: path, Action: "delete", Result: "planned"})
+			continue
+		}
+		if f.ScreenID != "" {
+			if err := s.client.DeleteScreen(s.ctx, f.ScreenID); err != nil {
+				return err
+			}
+		}
+		s.state.remove(path)
+		s.log.event("pruned", logFields{"project": f.Project, "screen": f.Screen, "path": path}, "deleted a screen %q of a project %q", f.Screen, f.Project)
+		s.record(fileResult{Project: f.Project, Screen: f.Screen, File: path, Action: "delete", Result: "deleted"})
+	}
+	return nil
+}
+
+// unfoundProjects warns of the --project whose artboards are not found in the walk.
+func (s *syncer) unfoundProjects() []error {
+	var errs []error
+	for _, name := range s.opts.Projects {
+		if !s.found[name] {
+			s.log.event("warning", logFields{"project": name, "message": "no artboards"}, "warning: no artboards of a project %q given by --project", name)
+			errs = append(errs, fmt.Errorf("a project %q given by --project is not found in the directories", name))
+		}
+	}
+	return errs
+}
+
+// project finds the project named name, creating it with --create-missing-projects.
+func (s *syncer) project(name string) (Project, bool, error) {
+	s.projectsMu.Lock()
+	defer s.projectsMu.Unlock()
+	if p, ok := s.projects[name]; ok {
+		return p, true, nil
+	}
+	if !s.opts.CreateMissing {
+		s.missing[name] = true
+		return Project{}, false, nil
+	}
+	if s.opts.DryRun {
+		s.log.event("would_create_project", logFields{"project": name}, "would create a project %q", name)
+		p := Project{Name: name}
+		s.projects[name] = p
+		return p, true, nil
+	}
+	// created while holding the lock not to create the same project twice
+	p, err := s.newProject.create(s.ctx, s.client, s.log, name)
+	if err != nil {
+		return Project{}, false, err
+	}
+	s.log.event("project_created", logFields{"project": name, "id": p.ID}, "created a project %q", name)
+	s.projects[name] = p
+	return p, true, nil
+}
+
+// knownProject returns the project of the name if it is found or created, or a project of only the name.
+func (s *syncer) knownProject(name string) Project {
+	s.projectsMu.Lock()
+	defer s.projectsMu.Unlock()
+	if p, ok := s.projects[name]; ok {
+		return p
+	}
+	return Project{Name: name}
+}
+
+func (s *syncer) enqueue(job uploadJob) error {
+	if s.opts.DryRun {
+		s.log.event("would_upload", logFields{"project": job.project.Name, "screen": job.screen, "path": job.path}, "would upload %s to a screen %q of a project %q", job.path, job.screen, job.project.Name)
+		s.record(fileResult{Project: job.project.Name, Screen: job.screen, File: job.path, Action: "upload", Result: "planned"})
+		s.planMu.Lock()
+		s.planned[job.project.Name]++
+		s.plannedBytes += fileSize(job.path)
+		s.planMu.Unlock()
+		return nil
+	}
+	if s.opts.BySize {
+		info, err := os.Stat(job.path)
+		if err != nil {
+			return err
+		}
+		job.size = info.Size()
+		job.prioritized = s.opts.BySizeThreshold <= 0 || job.size < int64(s.opts.BySizeThreshold)
+	}
+	s.progress.queued()
+	s.queue.push(job)
+	return nil
+}
+
+// printPlan prints the summary of a --dry-run.
+func (s *syncer) printPlan() {
+	screens := 0
+	for _, n := range s.planned {
+		screens += n
+	}
+	s.log.event("plan", logFields{"session_id": s.artboard.SessionID, "screens": screens, "projects": len(s.planned), "unknown_project_files": s.unknowns, "bytes": s.plannedBytes, "results": s.sortedResults()},
+		"Would upload %d screens across %d projects, %d files skipped (unknown project)\nTotal size: %s",
+		screens, len(s.planned), s.unknowns, units.Base2Bytes(s.plannedBytes))
+}
+
+// enqueueRetries queues only the artboards listed in the --retry-from file.
+func (s *syncer) enqueueRetries() error {
+	entries, err := loadRetryEntries(s.opts.RetryFrom)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !s.selected(e.Project) {
+			continue
+		}
+		project, ok, err := s.project(e.Project)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			s.skip(Project{Name: e.Project}, e.Screen, e.Path, "unknown_project", "a project %q is not exist", e.Project)
+			continue
+		}
+		if err := s.enqueue(uploadJob{project: project, screen: e.Screen, path: e.Path}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runWorkers starts --ramp-start workers and adds one every --ramp-interval
+// until --parallelism workers are running or the queue is drained.
+func (s *syncer) runWorkers(wg *sync.WaitGroup) {
+	defer wg.Done()
+	start := s.opts.RampStart
+	if start <= 0 || start > s.opts.Concurrency {
+		start = s.opts.Concurrency
+	}
+	for i := 0; i < start; i++ {
+		wg.Add(1)
+		go s.work(wg)
+	}
+	if start == s.opts.Concurrency || s.opts.RampInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.opts.RampInterval)
+	defer ticker.Stop()
+	for n := start; n < s.opts.Concurrency; n++ {
+		select {
+		case <-ticker.C:
+			wg.Add(1)
+			go s.work(wg)
+		case <-s.queue.drained():
+			return
+		}
+	}
+}
+
+func (s *syncer) work(wg *sync.WaitGroup) {
+	defer wg.Done()
+	for {
+		job, ok := s.queue.pop()
+		if !ok {
+			return
+		}
+		entry := retryEntry{Project: job.project.Name, Screen: job.screen, Path: job.path}
+		run := s.projectRun(job.project)
+		s.progress.started(job.project.Name, job.screen)
+		s.stats.started()
+		var uploaded Screen
+		err := run.start(s.prepareProject)
+		if err == nil {
+			uploaded, err = s.upload(job.project, job.screen, job.path)
+		}
+		size := fileSize(job.path)
+		run.done(size, err)
+		done, total := s.progress.finished(job.project.Name, job.screen, err)
+		if err != nil {
+			s.log.warn("progress", logFields{"project": job.project.Name, "screen": job.screen, "done": done, "total": total, "error": err.Error()},
+				"[%d/%d] failed to upload a screen %q of a project %q", done, total, job.screen, job.project.Name)
+		} else {
+			s.log.event("progress", logFields{"project": job.project.Name, "screen": job.screen, "done": done, "total": total, "error": nil},
+				"[%d/%d] uploaded a screen %q of a project %q", done, total, job.screen, job.project.Name)
+		}
+		s.stats.add(size, err)
+		s.recordUpload(job, err)
+		if err != nil {
+			if err := s.failed(entry, err); err != nil {
+				s.setError(err)
+			}
+			continue
+		}
+		if s.state != nil {
+			f := job.file
+			if f.ModTime.IsZero() {
+				// queued from --retry-from without walking
+				if f, err = statFile(job.path, s.opts.DedupStrategy); err != nil {
+					s.setError(err)
+					continue
+				}
+			}
+			f.Project, f.Screen, f.ScreenID = job.project.Name, job.screen, uploaded.ID
+			s.state.set(job.path, f)
+		}
+		if s.opts.RetryFrom != "" {
+			// uploaded: drop it from the --retry-from file
+			s.retryMu.Lock()
+			err := updateRetryEntries(s.opts.RetryFrom, func(entries []retryEntry) []retryEntry {
+				return removeRetryEntry(entries, entry)
+			})
+			s.retryMu.Unlock()
+			if err != nil {
+				s.setError(err)
+			}
+		}
+	}
+}
+
+// prepareProject updates the project and runs the --project-start-hook
+// before the first screen of the project is uploaded.
+func (s *syncer) prepareProject(run *projectRun) error {
+	fields, err := s.newProject.updateFields(run.project.Name)
+	if err != nil {
+		return err
+	}
+	if len(fields) > 0 {
+		if err := s.client.UpdateProject(s.ctx, run.project, fields); err != nil {
+			return err
+		}
+	}
+	if s.opts.ProjectStartHook != "" {
+		return runHook(s.opts.ProjectStartHook, run.env()...)
+	}
+	return nil
+}
+
+// fileSize returns the size of the file, or 0 if it cannot be read.
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+func (s *syncer) setError(err error) {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	s.errs = append(s.errs, err)
+}
+
+// failed returns the error of the failed upload, for the sync to fail after the other uploads.
+// With --upload-retry-file, the upload is also recorded in the file to retry it with --retry-from.
+func (s *syncer) failed(e retryEntry, err error) error {
+	if s.opts.RetryFile == "" {
+		return fmt.Errorf("failed to upload %s: %s", e.Path, err)
+	}
+	s.log.warn("upload_failed", logFields{"project": e.Project, "screen": e.Screen, "path": e.Path, "error": err.Error()}, "failed to upload %s: %s", e.Path, err)
+	s.retryMu.Lock()
+	defer s.retryMu.Unlock()
+	if err := updateRetryEntries(s.opts.RetryFile, func(entries []retryEntry) []retryEntry {
+		return append(removeRetryEntry(entries, e), e)
+	}); err != nil {
+		return err
+	}
+	return fmt.Errorf("failed to upload %s: %s (recorded in %s)", e.Path, err, s.opts.RetryFile)
+}
+
+func (s *syncer) upload(project Project, screenName, path string) (Screen, error) {
+	fields, err := s.artboard.fields(s.ctx, s.client, project, screenName, path)
+	if err != nil {
+		return Screen{}, err
+	}
+	if err := s.addSubdirectory(fields, project, path); err != nil {
+		return Screen{}, err
+	}
+	files, err := s.artboard.files(path)
+	if err != nil {
+		return Screen{}, err
+	}
+	key := s.screenKey(fields, screenName)
+	var existingID string
+	if !s.opts.ForceRecreate {
+		if existingID, err = s.existingScreenID(project, key); err != nil {
+			return Screen{}, err
+		}
+	}
+	var uploaded Screen
+	chunked := s.opts.ChunkSize > 0 && fileSize(path) > int64(s.opts.ChunkSize)
+	if chunked {
+		uploaded, err = uploadScreenChunked(s.ctx, s.client, s.log, project, screenName, path, existingID, fields, files, int64(s.opts.ChunkSize))
+	}
+	if !chunked || err == prott.ErrChunkedUploadUnsupported {
+		uploaded, err = uploadScreen(s.ctx, s.client, s.log, project, screenName, path, existingID, fields, files, func(sent, total int64) {
+			s.progress.sending(project.Name, screenName, sent, total)
+		})
+	}
+	if err != nil {
+		return Screen{}, err
+	}
+	if uploaded.ID == "" {
+		return uploaded, nil
+	}
+	s.rememberScreen(project, key, uploaded.ID)
+	s.uploaded(project, uploaded, screenName)
+	if s.opts.ExportMetadata {
+		if err := writeMetadata(s.opts.MetadataDir, project, uploaded, path); err != nil {
+			return Screen{}, err
+		}
+	}
+	if err := s.addLink(project, uploaded, path); err != nil {
+		return Screen{}, err
+	}
+	return uploaded, s.artboard.attach(s.ctx, s.client, uploaded, path)
+}
+
+// screenKey returns the key of the screen of the name uploaded with the fields.
+// With --subdirectories=group, the screens of the same name in different groups are different screens.
+func (s *syncer) screenKey(fields url.Values, name string) screenKey {
+	if s.opts.Subdirectories == "group" {
+		return screenKey{Group: fields.Get("screen[group_id]"), Name: name}
+	}
+	return screenKey{Name: name}
+}
+
+// existingScreenID returns the ID of the screen of the same name (and group) on Prott to update, or "" to create one.
+// The screens are fetched once per project.
+func (s *syncer) existingScreenID(project Project, key screenKey) (string, error) {
+	s.screenListsMu.Lock()
+	defer s.screenListsMu.Unlock()
+	ids, ok := s.screenLists[project.Name]
+	if !ok {
+		var err error
+		if ids, err = getScreenList(s.ctx, s.client, project.ID, s.opts.Subdirectories == "group"); err != nil {
+			return "", err
+		}
+		s.screenLists[project.Name] = ids
+	}
+	return ids[key], nil
+}
+
+// rememberScreen records the uploaded screen as existing on Prott,
+// so that the next upload of the artboard (e.g. with --watch) replaces it instead of creating another.
+func (s *syncer) rememberScreen(project Project, key screenKey, id string) {
+	s.screenListsMu.Lock()
+	defer s.screenListsMu.Unlock()
+	if ids, ok := s.screenLists[project.Name]; ok {
+		ids[key] = id
+	}
+	// not fetched yet: the next existingScreenID fetches it with the screen
+}
+
+func (s *syncer) hasExistingScreens(project Project) (bool, error) {
+	s.existingMu.Lock()
+	defer s.existingMu.Unlock()
+	if skip, ok := s.existing[project.Name]; ok {
+		return skip, nil
+	}
+	if project.ID == "" {
+		// would be created by --dry-run
+		return false, nil
+	}
+	screens, err := s.client.Screens(s.ctx, project.ID)
+	if err != nil {
+		return false, err
+	}
+	s.existing[project.Name] = len(screens) >= s.opts.MinExistingScreens
+	if s.existing[project.Name] {
+		s.log.event("project_has_screens", logFields{"project": project.Name, "screens": len(screens)}, "a project %q already has %d screens", project.Name, len(screens))
+	}
+	return s.existing[project.Name], nil
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wacul/protter/prott"
+)
+
+// fakePrott records the screen uploads to a project of no screens, and answers each with a new screen.
+type fakePrott struct {
+	mu       sync.Mutex
+	requests []string // method and path
+	created  int
+	groups   int
+}
+
+func (f *fakePrott) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	switch {
+	case r.Method == "GET":
+		w.Write([]byte(`[]`))
+	case strings.HasSuffix(r.URL.Path, "/groups.json"):
+		f.groups++
+		w.Write([]byte(`{"id":"g` + strconv.Itoa(f.groups) + `","name":"` + r.FormValue("group[name]") + `"}`))
+	case r.URL.Path == "/api/sketch_app/screens.json":
+		f.created++
+		w.Write([]byte(`{"id":"` + strconv.Itoa(f.created) + `","name":"` + r.FormValue("screen[name]") + `"}`))
+	default:
+		w.Write([]byte(`{"id":"` + filepath.Base(r.URL.Path[:len(r.URL.Path)-len(".json")]) + `"}`))
+	}
+}
+
+func (f *fakePrott) count(request string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, r := range f.requests {
+		if r == request {
+			n++
+		}
+	}
+	return n
+}
+
+func newTestSyncer(t *testing.T, handler http.Handler, opts *syncOptions) *syncer {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	client := prott.NewClient(srv.Client())
+	client.BaseURL = srv.URL
+	return &syncer{
+		ctx:         context.Background(),
+		client:      client,
+		log:         newLogger(ioutil.Discard, "text", logNormal),
+		opts:        opts,
+		artboard:    &artboardOptions{},
+		newProject:  &projectOptions{},
+		projects:    map[string]Project{},
+		missing:     map[string]bool{},
+		found:       map[string]bool{},
+		planned:     map[string]int{},
+		queue:       newUploadQueue(),
+		existing:    map[string]bool{},
+		runs:        map[string]*projectRun{},
+		screenIDs:   map[string]map[string]string{},
+		screenLists: map[string]map[screenKey]string{},
+		groups:      map[string]map[string]string{},
+		names:       map[string]map[string]string{},
+	}
+}
+
+// writeArtboard writes a 1x1 PNG to path.
+func writeArtboard(t *testing.T, path string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUploadReplacesScreenCreatedInRun(t *testing.T) {
+	fake := &fakePrott{}
+	s := newTestSyncer(t, fake, &syncOptions{Subdirectories: "project"})
+	path := filepath.Join(t.TempDir(), ".exportedArtboards", "App", "Home.png")
+	writeArtboard(t, path)
+	project := Project{ID: "p", Name: "App"}
+
+	for i := 0; i < 3; i++ {
+		screen, err := s.upload(project, "Home", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if screen.ID != "1" {
+			t.Errorf("upload %d: got a screen %q, want the screen 1 created first", i, screen.ID)
+		}
+	}
+	if n := fake.count("POST /api/sketch_app/screens.json"); n != 1 {
+		t.Errorf("created %d screens, want 1", n)
+	}
+	if n := fake.count("PATCH /api/sketch_app/screens/1.json"); n != 2 {
+		t.Errorf("replaced the screen %d times, want 2", n)
+	}
+}
+
+func TestUploadTellsScreensInGroupsApart(t *testing.T) {
+	fake := &fakePrott{}
+	s := newTestSyncer(t, fake, &syncOptions{Subdirectories: "group"})
+	root := filepath.Join(t.TempDir(), ".exportedArtboards", "App")
+	project := Project{ID: "p", Name: "App"}
+
+	ids := map[string]bool{}
+	for _, group := range []string{"Onboarding", "Signup", "Onboarding"} {
+		path := filepath.Join(root, group, "step1.png")
+		writeArtboard(t, path)
+		screen, err := s.upload(project, "step1", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[screen.ID] = true
+	}
+	if len(ids) != 2 {
+		t.Errorf("uploaded to the screens %v, want one screen per group", ids)
+	}
+	if n := fake.count("POST /api/sketch_app/screens.json"); n != 2 {
+		t.Errorf("created %d screens, want 2", n)
+	}
+}
+
+func TestAddSkipsSameNameInOtherTagDirectory(t *testing.T) {
+	s := newTestSyncer(t, &fakePrott{}, &syncOptions{Subdirectories: "tag", Concurrency: 1})
+	s.projects["App"] = Project{ID: "p", Name: "App"}
+	root := filepath.Join(t.TempDir(), ".exportedArtboards", "App")
+	for _, dir := range []string{"Onboarding", "Signup"} {
+		path := filepath.Join(root, dir, "step1.png")
+		writeArtboard(t, path)
+		if err := s.add("App", "step1", path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	results := s.sortedResults()
+	if len(results) != 1 || results[0].Result != "duplicate_name" || filepath.Base(filepath.Dir(results[0].File)) != "Signup" {
+		t.Errorf("got the results %+v, want Signup/step1.png skipped as duplicate_name", results)
+	}
+}
+
+func TestAddSkipsOutOfTimeWindow(t *testing.T) {
+	s := newTestSyncer(t, &fakePrott{}, &syncOptions{CreateMissing: true})
+	s.opts.UploadAfter.Time = time.Now().Add(time.Hour)
+	path := filepath.Join(t.TempDir(), "App", "step1.png")
+	writeArtboard(t, path)
+	if err := s.add("App", "step1", path); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.projects["App"]; ok {
+		t.Error("created a project for an artboard out of the window")
+	}
+	results := s.sortedResults()
+	if len(results) != 1 || results[0].Result != "out_of_time_window" {
+		t.Errorf("got the results %+v, want step1.png skipped as out_of_time_window", results)
+	}
+	if r := s.runs["App"]; r == nil || r.skipped != 1 {
+		t.Errorf("got the run %+v, want 1 skipped", r)
+	}
+}
+
+func TestFailedUploadRecordedInRetryFileFailsSync(t *testing.T) {
+	retryFile := filepath.Join(t.TempDir(), "retry.json")
+	s := newTestSyncer(t, &fakePrott{}, &syncOptions{RetryFile: retryFile})
+	e := retryEntry{Project: "App", Screen: "Home", Path: "Home.png"}
+	if err := s.failed(e, errors.New("503 Service Unavailable")); err == nil {
+		t.Error("got no error, want the failure to fail the sync")
+	}
+	entries, err := loadRetryEntries(retryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0] != e {
+		t.Errorf("got the retry entries %+v, want %+v", entries, e)
+	}
+}
+
+func TestDryRunConfirmWatch(t *testing.T) {
+	fake := &fakePrott{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	client := prott.NewClient(srv.Client())
+	client.BaseURL = srv.URL
+	dir := t.TempDir()
+	writeArtboard(t, filepath.Join(dir, ".exportedArtboards", "App", "Home.png"))
+	opts := &syncOptions{Dirs: []string{dir}, Concurrency: 1, DedupStrategy: "none", DryRunConfirm: true, Watch: true, Yes: true, WatchDebounce: 10 * time.Millisecond}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- syncArtboards(ctx, client, newLogger(ioutil.Discard, "text", logNormal), []Project{{ID: "p", Name: "App"}}, opts, &artboardOptions{}, &projectOptions{})
+	}()
+	deadline := time.After(5 * time.Second)
+	for fake.count("POST /api/sketch_app/screens.json") == 0 {
+		select {
+		case err := <-done:
+			t.Fatalf("the sync returned before uploading: %v", err)
+		case <-deadline:
+			t.Fatal("the artboard is not uploaded after the plan is confirmed")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSplashUploadedOnlyToCreatedProject(t *testing.T) {
+	fake := &fakePrott{}
+	s := newTestSyncer(t, fake, &syncOptions{CreateMissing: true})
+	splash := filepath.Join(t.TempDir(), "splash.png")
+	writeArtboard(t, splash)
+	s.newProject.Splash = splash
+	s.projects["Existing"] = Project{ID: "e", Name: "Existing"}
+
+	if err := s.prepareProject(s.projectRun(s.projects["Existing"])); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.project("New"); err != nil {
+		t.Fatal(err)
+	}
+	if n := fake.count("PATCH /api/sketch_app/projects/e.json"); n != 0 {
+		t.Errorf("uploaded the splash to the existing project %d times, want none", n)
+	}
+	if n := fake.count("PATCH /api/sketch_app/projects/projects.json"); n != 1 {
+		t.Errorf("uploaded the splash to the created project %d times, want once", n)
+	}
+}
+
+func TestSyncRejectsNoMinExistingScreens(t *testing.T) {
+	opts := &syncOptions{Concurrency: 1, SkipExistingProjects: true, MinExistingScreens: 0}
+	err := syncArtboards(context.Background(), nil, newLogger(ioutil.Discard, "text", logNormal), nil, opts, &artboardOptions{}, &projectOptions{})
+	if err == nil || !strings.Contains(err.Error(), "--min-existing-screens") {
+		t.Errorf("got %v, want an error of --min-existing-screens", err)
+	}
+}
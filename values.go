@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// optionalInt is a kingpin.Value of an int flag which distinguishes "not set" from zero.
+type optionalInt struct {
+	value int
+	set   bool
+}
+
+func (i *optionalInt) Set(s string) error {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	i.value, i.set = v, true
+	return nil
+}
+
+func (i *optionalInt) String() string {
+	if !i.set {
+		return ""
+	}
+	return strconv.Itoa(i.value)
+}
+
+// optionalBool is a kingpin.Value of a bool flag which distinguishes "not set" from false.
+type optionalBool struct {
+	value bool
+	set   bool
+}
+
+func (b *optionalBool) Set(s string) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	b.value, b.set = v, true
+	return nil
+}
+
+func (b *optionalBool) String() string {
+	if !b.set {
+		return ""
+	}
+	return strconv.FormatBool(b.value)
+}
+
+func (b *optionalBool) IsBoolFlag() bool { return true }
+
+// safeArea is the safe area insets of a screen in pixels.
+// As a kingpin.Value it is given as "<top>,<bottom>,<left>,<right>".
+type safeArea struct {
+	Top    int `json:"top"`
+	Bottom int `json:"bottom"`
+	Left   int `json:"left"`
+	Right  int `json:"right"`
+
+	set bool
+}
+
+func (a *safeArea) Set(s string) error {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return fmt.Errorf("invalid safe area %q: expected <top>,<bottom>,<left>,<right>", s)
+	}
+	var insets [4]int
+	for i, p := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return fmt.Errorf("invalid safe area %q: %s", s, err)
+		}
+		insets[i] = v
+	}
+	a.Top, a.Bottom, a.Left, a.Right = insets[0], insets[1], insets[2], insets[3]
+	a.set = true
+	return nil
+}
+
+func (a *safeArea) String() string {
+	if !a.set {
+		return ""
+	}
+	return fmt.Sprintf("%d,%d,%d,%d", a.Top, a.Bottom, a.Left, a.Right)
+}
+
+// timeValue is a kingpin.Value of an ISO 8601 (RFC 3339) datetime flag.
+type timeValue struct {
+	time.Time
+}
+
+func (t *timeValue) Set(s string) error {
+	v, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return fmt.Errorf("invalid datetime %q: %s", s, err)
+	}
+	t.Time = v
+	return nil
+}
+
+func (t *timeValue) String() string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/kyoh86/fastwalk"
+)
+
+// minWatchPollInterval bounds how often --watch walks the directories with a short --watch-debounce.
+const minWatchPollInterval = 100 * time.Millisecond
+
+// watcher polls the .exportedArtboards directories for artboards written since the last poll.
+// Sketch writes an image in several writes, so a changed file is reported only once
+// it stays unchanged for the debounce.
+type watcher struct {
+	dirs     []string
+	debounce time.Duration
+
+	mu     sync.Mutex
+	seen   map[string]fileState // path -> size and modification time at the last poll
+	timers map[string]*time.Timer
+
+	pending sync.WaitGroup // the timers not stopped and their uploads
+}
+
+func newWatcher(dirs []string, debounce time.Duration) *watcher {
+	return &watcher{
+		dirs:     dirs,
+		debounce: debounce,
+		seen:     map[string]fileState{},
+		timers:   map[string]*time.Timer{},
+	}
+}
+
+// poll records the artboards in the directories, and calls changed for those new or modified since the last poll.
+func (w *watcher) poll(changed func(path string)) error {
+	current := map[string]bool{}
+	for _, dir := range w.dirs {
+		err := fastwalk.FastWalk(dir, func(path string, typ os.FileMode) error {
+			if _, _, err := parsePath(path); err != nil || !typ.IsRegular() {
+				return nil
+			}
+			f, err := statFile(path, "mtime")
+			if os.IsNotExist(err) {
+				return nil // removed while walking
+			} else if err != nil {
+				return err
+			}
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			current[path] = true
+			last, ok := w.seen[path]
+			w.seen[path] = f
+			if changed != nil && (!ok || !f.unchanged(last, "mtime")) {
+				changed(path)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for path := range w.seen {
+		if !current[path] {
+			delete(w.seen, path)
+		}
+	}
+	return nil
+}
+
+// run polls the directories until SIGINT or SIGTERM or the end of ctx, and calls upload for each artboard
+// left unchanged for the debounce after it is written.
+// The artboards still waiting for the debounce are uploaded before run returns.
+func (w *watcher) run(ctx context.Context, upload func(path string)) error {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	interval := w.debounce / 2
+	if interval < minWatchPollInterval {
+		interval = minWatchPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			// called with w.mu held
+			err := w.poll(func(path string) {
+				if t, ok := w.timers[path]; ok && t.Stop() {
+					t.Reset(w.debounce)
+					return
+				}
+				var t *time.Timer
+				w.pending.Add(1)
+				t = time.AfterFunc(w.debounce, func() {
+					defer w.pending.Done()
+					w.mu.Lock()
+					if w.timers[path] == t {
+						delete(w.timers, path)
+					}
+					w.mu.Unlock()
+					upload(path)
+				})
+				w.timers[path] = t
+			})
+			if err != nil {
+				return err
+			}
+		case <-stop:
+			return w.flush(upload)
+		case <-ctx.Done():
+			return w.flush(upload)
+		}
+	}
+}
+
+// flush uploads the artboards still waiting for the debounce, and waits for the uploads started by the timers.
+func (w *watcher) flush(upload func(path string)) error {
+	w.mu.Lock()
+	var pending []string
+	for path, t := range w.timers {
+		if t.Stop() {
+			pending = append(pending, path)
+		}
+	}
+	w.timers = map[string]*time.Timer{}
+	w.mu.Unlock()
+	for _, path := range pending {
+		upload(path)
+		w.pending.Done()
+	}
+	w.pending.Wait()
+	return nil
+}